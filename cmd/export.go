@@ -0,0 +1,130 @@
+package main
+
+
+import (
+  "flag"
+  "fmt"
+  "io"
+  "os"
+  "strings"
+
+  "gill-dashboard/pkg/stt_records"
+)
+
+
+type stringsFlag [] string
+
+func (flag_value *stringsFlag) String () string {
+  return strings.Join(*flag_value, ",")
+}
+
+func (flag_value *stringsFlag) Set (value string) error {
+  *flag_value = append(*flag_value, value)
+  return nil
+}
+
+
+type recordFlags struct {
+  /*
+    Flags shared by the commands which read and filter records from the local
+    STT CSV.
+  */
+  range_str   * string;
+  after_str   * string;
+  before_str  * string;
+  categories    stringsFlag;
+  query_str   * string;
+  input_path  * string;
+  output_path * string;
+}
+
+
+func addRecordFlags (flags * flag.FlagSet) * recordFlags {
+  record_flags := & recordFlags {
+    range_str:   flags.String("range", "", `only records in this date range, e.g. "last month" or 2024-Q3`),
+    after_str:   flags.String("after", "", "only records on or after this date (YYYY-MM-DD)"),
+    before_str:  flags.String("before", "", "only records on or before this date (YYYY-MM-DD)"),
+    input_path:  flags.String("input", stt_records.SttGetPath(), "STT CSV file to read"),
+    output_path: flags.String("output", "", "file to write to, instead of stdout"),
+    query_str:   flags.String("q", "", `filter query, e.g. 'category:Development -activity:Email'`),
+  }
+  flags.Var(&record_flags.categories, "category", "only records in this category; repeatable, or comma-separated")
+  return record_flags
+}
+
+
+func (record_flags *recordFlags) readRecords () (records [] stt_records.ActivityRecord, err error) {
+  /*
+    Read the records from the input CSV, without syncing it first, and filter
+    them.
+  */
+
+  if err = stt_records.SttInit(); err != nil { return nil, err }
+
+  filter := stt_records.ActivityRecordFilter {}
+  if err = filter.SetRange(*record_flags.range_str); err != nil { return nil, err }
+  if *record_flags.after_str != "" {
+    if err = filter.SetAfter(*record_flags.after_str); err != nil { return nil, err }
+  }
+  if *record_flags.before_str != "" {
+    if err = filter.SetBefore(*record_flags.before_str); err != nil { return nil, err }
+  }
+  if err = filter.SetQuery(*record_flags.query_str); err != nil { return nil, err }
+  for _, categories_str := range record_flags.categories {
+    filter.AddCategories(categories_str)
+  }
+
+  csv_io_reader, err := os.Open(*record_flags.input_path)
+  if err != nil { return nil, err }
+  defer csv_io_reader.Close()
+
+  records, err = stt_records.SttCsvReadRange(csv_io_reader, filter.After, filter.Before)
+  if err != nil { return nil, fmt.Errorf("STT parsing error: %w", err) }
+
+  return filter.Apply(records), nil
+}
+
+
+func (record_flags *recordFlags) writeOutput (write func (io.Writer) error) error {
+  if *record_flags.output_path == "" {
+    return write(os.Stdout)
+  }
+
+  output, err := os.Create(*record_flags.output_path)
+  if err != nil { return err }
+
+  err = write(output)
+  if close_err := output.Close(); err == nil {
+    err = close_err
+  }
+  return err
+}
+
+
+func runExport (args [] string) (err error) {
+  /*
+    gill-dashboard export [-format json|ndjson|markdown|csv] [-totals | -group KEYS]
+      [-range RANGE] [-after YYYY-MM-DD] [-before YYYY-MM-DD]
+      [-category NAME]... [-q QUERY] [-input PATH] [-output PATH]
+  */
+
+  flags        := flag.NewFlagSet("export", flag.ExitOnError)
+  format       := flags.String("format", "json", "export format: " + strings.Join(stt_records.EXPORT_FORMATS, ", "))
+  totals       := flags.Bool("totals", false, "export per-activity totals instead of records")
+  group_str    := flags.String("group", "", "export aggregates grouped by these comma-separated keys: " + strings.Join(stt_records.AGGREGATE_KEYS, ", "))
+  record_flags := addRecordFlags(flags)
+  flags.Parse(args)
+
+  group_by, err := stt_records.ParseAggregateKeys(*group_str)
+  if err != nil { return err }
+
+  records, err := record_flags.readRecords()
+  if err != nil { return err }
+
+  return record_flags.writeOutput(func (output io.Writer) error {
+    if len(group_by) > 0 {
+      return stt_records.ActivityRecordsExportGroups(output, records, *format, group_by)
+    }
+    return stt_records.ActivityRecordsExport(output, records, *format, *totals)
+  })
+}
@@ -2,9 +2,12 @@ package main
 
 import (
   "os"
+  "bytes"
+  "errors"
   "fmt"
   "log"
   "time"
+  "strconv"
   "strings"
   "net/http"
 
@@ -14,11 +17,27 @@ import (
   "gill-dashboard/web"
 )
 
-var records [] stt_records.ActivityRecord;
-
 func main () {
   godotenv.Load()  // error silently
 
+  if len(os.Args) > 1 {
+    commands := map [string] func ([] string) error {
+      "export":    runExport,
+      "merge":     runMerge,
+      "timesheet": runTimesheet,
+    }
+
+    command, found := commands[os.Args[1]]
+    if ! found {
+      log.Fatalln("unknown command:", os.Args[1])
+    }
+
+    if err := command(os.Args[2:]); err != nil {
+      log.Fatalln(os.Args[1], "error:", err)
+    }
+    return
+  }
+
   err, _ := stt_records.SttSync()
   if err != nil {
     log.Fatalln("sync error:", err)
@@ -43,8 +62,19 @@ func main () {
     log.Fatalln("STT parsing error:", err)
   }
 
-  // Determine the final record date. That will be the last date of the
-  // week-long time window for the last-week metric.
+  // Names keep the colors they were given before, and new ones are saved
+  colors_path := stt_records.SttGetColorsPath()
+  if err := stt_records.ColorAssignmentsLoad(colors_path); err != nil {
+    log.Println("colors error:", err)
+  }
+  if stt_records.ColorAssignRecords(year_records) > 0 {
+    if err := stt_records.ColorAssignmentsSave(colors_path); err != nil {
+      log.Println("colors error:", err)
+    }
+  }
+
+  // Determine the final record date. The week it is in (see
+  // stt_records.WeekStart) is the time window for the last-week metric.
   //
   var final_date time.Time = time.Time {}
   for _, record := range year_records {
@@ -54,30 +84,278 @@ func main () {
     }
   }
 
-  week_start := final_date.AddDate(0, 0, -7)
+  week_start := stt_records.WeekStart(final_date)
+  week_end   := week_start.AddDate(0, 0, 6)
+
+  week_records := stt_records.ActivityRecordsFilterTimeRange(year_records, &week_start, &week_end)
+  day_records  := stt_records.ActivityRecordsFilterTimeRange(year_records, &final_date, &final_date)
+  fmt.Println("Number of records, Final Week:", len(week_records))
 
-  records := stt_records.ActivityRecordsFilterTimeRange(year_records, &week_start, nil)
-  fmt.Println("Number of records, Final Week:", len(records))
-  records  = stt_records.ActivityRecordsFilterCategories(
-      records, "Productivity", "Development",
-    )
+  // The week's records shown by default are those matching DASHBOARD_QUERY;
+  // pages take a "q" parameter to query differently, and a "range" parameter
+  // (see stt_records.ParseDateRange) for records other than the week's.
 
-  fmt.Println("Number of records, Week Productivity:", len(records))
+  default_query_str, found := os.LookupEnv("DASHBOARD_QUERY")
+  if ! found {
+    default_query_str = "category:Productivity,Development"
+  }
+  default_query, err := stt_records.ParseRecordQuery(default_query_str)
+  if err != nil {
+    log.Fatalln("DASHBOARD_QUERY error:", err)
+  }
+
+  fmt.Println("Number of records, Week Query:", len(default_query.Filter(week_records)))
   fmt.Println()
 
+  requestQuery := func (res http.ResponseWriter, req * http.Request) (* stt_records.RecordQuery, bool) {
+    /*
+      The "q" parameter's query, or DASHBOARD_QUERY. Either is parsed for
+      each request, so that relative dates like on:"last week" are of the
+      day it's made.
+    */
+    query_str := req.URL.Query().Get("q")
+    if query_str == "" {
+      query_str = default_query_str
+    }
+    query, err := stt_records.ParseRecordQuery(query_str)
+    if err != nil {
+      http.Error(res, err.Error(), http.StatusBadRequest)
+      return nil, false
+    }
+    return query, true
+  }
+
+  queryRecords := func (
+    res            http.ResponseWriter,
+    req          * http.Request,
+    base_records [] stt_records.ActivityRecord,
+  ) ([] stt_records.ActivityRecord, bool) {
+    /*
+      Filter base_records, or the records in the "range" parameter if there is
+      one, by the request's query.
+    */
+
+    range_records := base_records
+    if range_str := req.URL.Query().Get("range"); range_str != "" {
+      date_range, err := stt_records.ParseDateRange(range_str, time.Now())
+      if err != nil {
+        http.Error(res, err.Error(), http.StatusBadRequest)
+        return nil, false
+      }
+      range_records = stt_records.ActivityRecordsFilterTimeRange(
+          year_records, date_range.FirstDay(), date_range.LastDay(),
+        )
+    }
+
+    query, ok := requestQuery(res, req)
+    if ! ok { return nil, false }
+
+    return query.Filter(range_records), true
+  }
+
   http.HandleFunc("/", func (res http.ResponseWriter, req * http.Request) {
-    web.ServeIndex(res, req, records)
+    records, ok := queryRecords(res, req, week_records)
+    if ! ok { return }
+    heading := "Productivity: week " + stt_records.WeekLabel(week_start)
+    if req.URL.Query().Has("range") || req.URL.Query().Has("q") {
+      heading = "Records"
+    }
+
+    // The calendar is always of the year, by the same query
+    query, ok := requestQuery(res, req)
+    if ! ok { return }
+
+    web.ServeIndex(res, req, records, query.Filter(year_records), heading)
+  })
+
+  http.HandleFunc("/export", func (res http.ResponseWriter, req * http.Request) {
+    web.ServeExport(res, req, year_records)
+  })
+
+  http.HandleFunc("/calendar.ics", func (res http.ResponseWriter, req * http.Request) {
+    web.ServeCalendar(res, req, year_records)
+  })
+
+  http.HandleFunc("/timesheet.xlsx", func (res http.ResponseWriter, req * http.Request) {
+    web.ServeTimesheet(res, req, year_records)
+  })
+
+  http.HandleFunc("/changes", func (res http.ResponseWriter, req * http.Request) {
+    diff, old_snapshot, new_snapshot, err := stt_records.SttSnapshotDiffLatest()
+    if err != nil {
+      http.Error(res, err.Error(), http.StatusInternalServerError)
+      return
+    }
+    web.ServeChanges(res, req, diff, old_snapshot, new_snapshot)
   })
 
+  chartRequest := func (
+    res   http.ResponseWriter,
+    req * http.Request,
+  ) (stt_records.Chart, [] stt_records.ActivityRecord, * stt_records.ActivityRecordChartOptions, bool) {
+    /*
+      The chart of the "chart" parameter (a pie by default), the records to
+      plot in it, and its options, from the request's other parameters; or
+      false, having responded with a 400, if any of those aren't valid. Charts
+      are of the week's records by default, apart from the calendar and the
+      line chart (of the year's), and the timeline and clock (of the final
+      day's).
+    */
+
+    bad := func (err error) (stt_records.Chart, [] stt_records.ActivityRecord, * stt_records.ActivityRecordChartOptions, bool) {
+      http.Error(res, err.Error(), http.StatusBadRequest)
+      return nil, nil, nil, false
+    }
+    params := req.URL.Query()
+
+    chart_name := params.Get("chart")
+    if chart_name == "" {
+      chart_name = "pie"
+    }
+    chart, err := stt_records.LookupChart(chart_name)
+    if err != nil { return bad(err) }
+
+    base_records := week_records
+    switch chart_name {
+    case "calendar", "lines":
+      base_records = year_records
+    case "timeline", "clock":
+      base_records = day_records
+    }
+    records, ok := queryRecords(res, req, base_records)
+    if ! ok { return nil, nil, nil, false }
+
+    options := stt_records.ActivityRecordChartOptions {
+      Group_by: params.Get("group"),
+      Theme:    params.Get("theme"),
+      Average:  params.Has("average"),
+      Legend:   params.Has("legend"),
+      Donut:    params.Has("donut"),
+      Order:    params.Get("order"),
+    }
+    if err := stt_records.ValidateGroupBy(chart_name, options.Group_by); err != nil { return bad(err) }
+    if err := stt_records.ValidateTheme(options.Theme);      err != nil { return bad(err) }
+    if err := stt_records.ValidatePieOrder(options.Order);   err != nil { return bad(err) }
+
+    // "size" is WIDTHxHEIGHT, or just WIDTH; without it, each chart has its
+    // own default
+    if size_str := params.Get("size"); size_str != "" {
+      options.Width, options.Height, err = stt_records.ParseChartSize(size_str)
+      if err != nil { return bad(err) }
+    }
+
+    // "rolling" is the line chart's averaging windows, e.g. 7,28
+    if rolling_str := params.Get("rolling"); rolling_str != "" {
+      for _, window_str := range strings.Split(rolling_str, ",") {
+        rolling_days, err := strconv.Atoi(strings.TrimSpace(window_str))
+        if err != nil || rolling_days < 1 {
+          return bad(errors.New("rolling must be positive numbers of days, separated by commas"))
+        }
+        options.Rolling_days = append(options.Rolling_days, rolling_days)
+      }
+    }
+
+    if other_str := params.Get("other"); other_str != "" {
+      other_percent, err := strconv.ParseFloat(other_str, 64)
+      if err != nil || other_percent < 0 || other_percent > 100 {
+        return bad(errors.New("other must be a percentage"))
+      }
+      options.Other_percent = other_percent
+    }
+
+    // "compare" is another date range, whose records by the same query are
+    // the pie's inner ring
+    if compare_str := params.Get("compare"); compare_str != "" {
+      compare_range, err := stt_records.ParseDateRange(compare_str, time.Now())
+      if err != nil { return bad(err) }
+      query, ok := requestQuery(res, req)
+      if ! ok { return nil, nil, nil, false }
+
+      options.Compare_records = query.Filter(stt_records.ActivityRecordsFilterTimeRange(
+          year_records, compare_range.FirstDay(), compare_range.LastDay(),
+        ))
+      options.Compare_label = compare_range.String()
+    }
+
+    if targets_str := params.Get("targets"); targets_str != "" {
+      targets, err := stt_records.ParsePieTargets(targets_str)
+      if err != nil { return bad(err) }
+      options.Targets = targets
+    }
+
+    // "gap" is the longest gap between records the transitions chart counts
+    // as a transition
+    if gap_str := params.Get("gap"); gap_str != "" {
+      max_gap, err := time.ParseDuration(gap_str)
+      if err != nil || max_gap < 0 {
+        return bad(errors.New("gap must be a duration, like 30m"))
+      }
+      options.Max_gap = &max_gap
+    }
+
+    return chart, records, &options, true
+  }
+
   http.HandleFunc("/img.svg", func (res http.ResponseWriter, req * http.Request) {
-    svg_string_builder := strings.Builder {}
-    svg_string_builder.WriteString(
-        stt_records.ActivityRecordsPlotPieChart(records, nil),
-      )
+    chart, records, options, ok := chartRequest(res, req)
+    if ! ok { return }
 
     res.Header().Set("Content-Type", "image/svg+xml")
-    fmt.Fprintf(res, "%s", svg_string_builder.String())
+    if err := chart.Render(res, records, options); err != nil {
+      log.Println("/img.svg:", err)
+    }
+  })
+
+  // The data each chart is drawn from, as an HTML table, by the same
+  // parameters
+  http.HandleFunc("/table.html", func (res http.ResponseWriter, req * http.Request) {
+    chart, records, options, ok := chartRequest(res, req)
+    if ! ok { return }
+
+    web.ServeChartTable(res, req, chart.Table(records, options))
   })
 
+  // The same charts as PNGs, at a "dpi" (96 by default, for the chart's
+  // size in pixels)
+  http.HandleFunc("/img.png", func (res http.ResponseWriter, req * http.Request) {
+    chart, records, options, ok := chartRequest(res, req)
+    if ! ok { return }
+
+    dpi := stt_records.RASTER_DPI_DEFAULT
+    if dpi_str := req.URL.Query().Get("dpi"); dpi_str != "" {
+      parsed_dpi, err := strconv.ParseFloat(dpi_str, 64)
+      if err != nil || parsed_dpi < 24 || parsed_dpi > 600 {
+        http.Error(res, "dpi must be a number from 24 to 600", http.StatusBadRequest)
+        return
+      }
+      dpi = parsed_dpi
+    }
+
+    var png_buffer bytes.Buffer
+    err := stt_records.ChartRenderPNG(&png_buffer, chart, records, options, dpi)
+    if errors.Is(err, stt_records.ErrRasterTooLarge) {
+      http.Error(res, err.Error(), http.StatusBadRequest)
+      return
+    } else if err != nil {
+      http.Error(res, err.Error(), http.StatusInternalServerError)
+      return
+    }
+
+    res.Header().Set("Content-Type", "image/png")
+    res.Write(png_buffer.Bytes())
+  })
+
+  // Each chart used to have an address of its own, like /bars.svg; those
+  // are the same chart at /img.svg now, with the same parameters
+  for _, chart_name := range [] string {
+    "calendar", "heatmap", "bars", "lines", "timeline", "clock", "sunburst", "treemap", "transitions",
+  } {
+    http.HandleFunc("/" + chart_name + ".svg", func (res http.ResponseWriter, req * http.Request) {
+      params := req.URL.Query()
+      params.Set("chart", chart_name)
+      http.Redirect(res, req, "/img.svg?" + params.Encode(), http.StatusMovedPermanently)
+    })
+  }
+
   http.ListenAndServe(":8080", nil)
 }
@@ -0,0 +1,48 @@
+package main
+
+
+import (
+  "flag"
+  "fmt"
+  "os"
+
+  "gill-dashboard/pkg/stt_records"
+)
+
+
+func runMerge (args [] string) (err error) {
+  /*
+    gill-dashboard merge [-output PATH] FILE...
+
+    Merge STT CSV files, such as snapshots or exports from more than one
+    phone, into one STT CSV file, for importing back into STT. Where files
+    disagree about a record, the later file wins.
+  */
+
+  flags       := flag.NewFlagSet("merge", flag.ExitOnError)
+  output_path := flags.String("output", "", "file to write to, instead of stdout")
+  flags.Parse(args)
+
+  if flags.NArg() == 0 {
+    return fmt.Errorf("merge needs one or more STT CSV files")
+  }
+  if err = stt_records.SttInit(); err != nil { return err }
+
+  record_sets := make([][] stt_records.ActivityRecord, 0, flags.NArg())
+  for _, input_path := range flags.Args() {
+    csv_io_reader, err := os.Open(input_path)
+    if err != nil { return err }
+
+    records, err := stt_records.SttCsvReadRange(csv_io_reader, nil, nil)
+    csv_io_reader.Close()
+    if err != nil { return fmt.Errorf("STT parsing error in %s: %w", input_path, err) }
+
+    record_sets = append(record_sets, records)
+  }
+
+  merged := stt_records.ActivityRecordsMerge(record_sets...)
+  if *output_path == "" {
+    return stt_records.SttCsvWrite(os.Stdout, merged)
+  }
+  return stt_records.SttCsvWriteFile(*output_path, merged)
+}
@@ -0,0 +1,45 @@
+package main
+
+
+import (
+  "flag"
+  "fmt"
+  "io"
+
+  "gill-dashboard/pkg/stt_records"
+)
+
+
+func runTimesheet (args [] string) (err error) {
+  /*
+    gill-dashboard timesheet -output PATH [-group activity|tag]
+      [-round DURATION] [-round_mode nearest|up|down]
+      [-range RANGE] [-after YYYY-MM-DD] [-before YYYY-MM-DD]
+      [-category NAME]... [-q QUERY] [-input PATH]
+  */
+
+  flags        := flag.NewFlagSet("timesheet", flag.ExitOnError)
+  group_by     := flags.String("group", "activity", "row grouping: activity or tag")
+  round_to     := flags.Duration("round", 0, "round each day's hours to a multiple of this duration, e.g. 15m")
+  round_mode   := flags.String("round_mode", "nearest", "rounding direction: nearest, up or down")
+  record_flags := addRecordFlags(flags)
+  flags.Parse(args)
+
+  if *record_flags.output_path == "" {
+    return fmt.Errorf("timesheet needs an -output path")
+  }
+
+  options := & stt_records.TimesheetOptions {
+    Group_by:   *group_by,
+    Round_to:   *round_to,
+    Round_mode: *round_mode,
+  }
+  if err = options.Validate(); err != nil { return err }
+
+  records, err := record_flags.readRecords()
+  if err != nil { return err }
+
+  return record_flags.writeOutput(func (output io.Writer) error {
+    return stt_records.ActivityRecordsWriteTimesheet(output, records, options)
+  })
+}
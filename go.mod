@@ -3,3 +3,8 @@ module gill-dashboard
 go 1.22.2
 
 require github.com/joho/godotenv v1.5.1
+
+require (
+	golang.org/x/image v0.24.0
+	golang.org/x/text v0.22.0 // indirect
+)
@@ -0,0 +1,271 @@
+package stt_records;
+
+
+import (
+  "encoding/json"
+  "fmt"
+  "sort"
+  "strings"
+  "time"
+)
+
+
+const AGGREGATE_UNCATEGORIZED string = "(uncategorized)"
+const AGGREGATE_UNTAGGED       string = "(untagged)"
+
+
+var AGGREGATE_KEYS [] string = [] string {
+  "day",
+  "week",
+  "month",
+  "weekday",
+  "hour",
+  "activity",
+  "category",
+  "tag",
+}
+
+
+type AggregateGroup struct {
+  /*
+    The records sharing one value for each group-by key. Keys holds those
+    values, in the order the keys were given. Durations are summarized over
+    each record's Duration, and Minutes sums their Duration_minutes.
+  */
+  Keys    [] string;
+  Count   int;
+  Minutes uint;
+  Sum     time.Duration;
+  Mean    time.Duration;
+  Median  time.Duration;
+  Max     time.Duration;
+
+  sort_keys [] string;
+  durations [] time.Duration;
+}
+
+
+type aggregateGroupJson struct {
+  Keys           [] string `json:"keys"`;
+  Count          int       `json:"count"`;
+  Minutes        uint      `json:"minutes"`;
+  Sum_seconds    float64   `json:"sum_seconds"`;
+  Mean_seconds   float64   `json:"mean_seconds"`;
+  Median_seconds float64   `json:"median_seconds"`;
+  Max_seconds    float64   `json:"max_seconds"`;
+}
+
+
+func (group AggregateGroup) MarshalJSON () ([] byte, error) {
+  /*
+    Durations are marshalled in seconds, rather than nanoseconds.
+  */
+  return json.Marshal(aggregateGroupJson {
+    Keys:           group.Keys,
+    Count:          group.Count,
+    Minutes:        group.Minutes,
+    Sum_seconds:    group.Sum.Seconds(),
+    Mean_seconds:   group.Mean.Seconds(),
+    Median_seconds: group.Median.Seconds(),
+    Max_seconds:    group.Max.Seconds(),
+  })
+}
+
+
+func validAggregateKey (key string) bool {
+  for _, valid_key := range AGGREGATE_KEYS {
+    if key == valid_key { return true }
+  }
+  return false
+}
+
+
+func ParseAggregateKeys (keys_str string) (keys [] string, err error) {
+  /*
+    Parse comma-separated group-by keys, such as "week,activity".
+  */
+
+  for _, key := range strings.Split(keys_str, ",") {
+    key = strings.ToLower(strings.TrimSpace(key))
+    if key == "" { continue }
+
+    if ! validAggregateKey(key) {
+      return nil, fmt.Errorf("unknown group-by key \"%s\", expected any of: %s", key, strings.Join(AGGREGATE_KEYS, ", "))
+    }
+
+    keys = append(keys, key)
+  }
+
+  return keys, nil
+}
+
+
+type aggregateValue struct {
+  value    string;
+  sort_key string;
+}
+
+
+func aggregateKeyValues (record * ActivityRecord, key string) [] aggregateValue {
+  /*
+    The values a record has for a key. Time keys are taken from when the
+    record started, within its day (see DayStart); weeks start on
+    STT_WEEK_START, and are labelled with their ISO 8601 week number.
+    Categories and tags can have several values, and a record counts toward
+    each of them.
+  */
+
+  switch key {
+  case "day":
+    day := record.DayStart().Format(STT_DATE_LAYOUT)
+    return [] aggregateValue { { day, day } }
+
+  case "week":
+    week_start := WeekStart(record.DayStart())
+    return [] aggregateValue { { WeekLabel(week_start), week_start.Format(STT_DATE_LAYOUT) } }
+
+  case "month":
+    month := record.DayStart().Format("2006-01")
+    return [] aggregateValue { { month, month } }
+
+  case "weekday":
+    weekday := record.DayStart().Weekday()
+    return [] aggregateValue { { weekday.String(), fmt.Sprint(WeekdayIndex(weekday)) } }
+
+  case "hour":
+    hour := fmt.Sprintf("%02d", record.Time_started.Hour())
+    return [] aggregateValue { { hour, hour } }
+
+  case "activity":
+    return [] aggregateValue { { record.Activity_name, record.Activity_name } }
+
+  case "category":
+    values := make([] aggregateValue, 0, len(record.Categories))
+    for _, category := range record.Categories {
+      if category != "" {
+        values = append(values, aggregateValue { category, category })
+      }
+    }
+    if len(values) == 0 {
+      return [] aggregateValue { { AGGREGATE_UNCATEGORIZED, "\uffff" } }
+    }
+    return values
+
+  case "tag":
+    tags := record.Tags()
+    if len(tags) == 0 {
+      return [] aggregateValue { { AGGREGATE_UNTAGGED, "\uffff" } }
+    }
+    values := make([] aggregateValue, len(tags))
+    for tag_i, tag := range tags {
+      values[tag_i] = aggregateValue { tag, tag }
+    }
+    return values
+  }
+
+  return nil
+}
+
+
+func ActivityRecordsAggregate (records [] ActivityRecord, keys ...string) (groups [] AggregateGroup, err error) {
+  /*
+    Group records by any combination of AGGREGATE_KEYS, and summarize the
+    duration of each group. Groups are sorted by their keys, chronologically
+    for time keys. With no keys, all records are one group.
+  */
+
+  for _, key := range keys {
+    if ! validAggregateKey(key) {
+      return nil, fmt.Errorf("unknown group-by key \"%s\", expected any of: %s", key, strings.Join(AGGREGATE_KEYS, ", "))
+    }
+  }
+
+  groups_by_key := make(map [string] *AggregateGroup)
+  group_order   := make([] *AggregateGroup, 0)
+
+  for record_i := range records {
+    record := &records[record_i]
+
+    // Take the cross product of the record's values for each key; a record
+    // in two categories, grouped by category and tag, is in 2 × (tag count)
+    // groups.
+
+    combinations := [][] aggregateValue { {} }
+    for _, key := range keys {
+      values            := aggregateKeyValues(record, key)
+      combinations_next := make([][] aggregateValue, 0, len(combinations) * len(values))
+      for _, combination := range combinations {
+        for _, value := range values {
+          combination_next := append(append([] aggregateValue {}, combination...), value)
+          combinations_next = append(combinations_next, combination_next)
+        }
+      }
+      combinations = combinations_next
+    }
+
+    for _, combination := range combinations {
+      values    := make([] string, len(combination))
+      sort_keys := make([] string, len(combination))
+      for value_i, value := range combination {
+        values[value_i]    = value.value
+        sort_keys[value_i] = value.sort_key
+      }
+
+      group_key    := strings.Join(values, "\x00")
+      group, found := groups_by_key[group_key]
+      if ! found {
+        group = &AggregateGroup { Keys: values, sort_keys: sort_keys }
+        groups_by_key[group_key] = group
+        group_order = append(group_order, group)
+      }
+
+      group.Count++
+      group.Minutes += record.Duration_minutes
+      group.Sum     += record.Duration
+      group.durations = append(group.durations, record.Duration)
+    }
+  }
+
+  // Summarize each group's durations
+
+  groups = make([] AggregateGroup, len(group_order))
+  for group_i, group := range group_order {
+    sort.Slice(group.durations, func (i, j int) bool { return group.durations[i] < group.durations[j] })
+
+    count        := len(group.durations)
+    group.Mean    = group.Sum / time.Duration(count)
+    group.Max     = group.durations[count-1]
+    if count % 2 == 1 {
+      group.Median = group.durations[count/2]
+    } else {
+      group.Median = (group.durations[count/2 - 1] + group.durations[count/2]) / 2
+    }
+
+    group.durations = nil
+    groups[group_i] = *group
+  }
+
+  sort.SliceStable(groups, func (i, j int) bool {
+    for key_i := range groups[i].sort_keys {
+      if groups[i].sort_keys[key_i] != groups[j].sort_keys[key_i] {
+        return groups[i].sort_keys[key_i] < groups[j].sort_keys[key_i]
+      }
+    }
+    return false
+  })
+
+  return groups, nil
+}
+
+
+func AggregateGroupsSortBySum (groups [] AggregateGroup) {
+  /*
+    Sort groups largest first, by their sum of minutes, then by their keys.
+  */
+  sort.SliceStable(groups, func (i, j int) bool {
+    if groups[i].Minutes != groups[j].Minutes {
+      return groups[i].Minutes > groups[j].Minutes
+    }
+    return strings.Join(groups[i].Keys, "\x00") < strings.Join(groups[j].Keys, "\x00")
+  })
+}
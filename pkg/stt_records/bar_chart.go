@@ -0,0 +1,209 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "time"
+)
+
+
+func activityRecordsDailySeries (records [] ActivityRecord, series_key string) (series_groups [] AggregateGroup, days [] time.Time, daily [][] float64) {
+  /*
+    Records' series, largest first, the days from the first record's to the
+    final record's, and each series' minutes on each of those days, as
+    daily[series_i][day_i], including the days without any.
+  */
+
+  series_groups, _ = ActivityRecordsAggregate(records, series_key)
+  AggregateGroupsSortBySum(series_groups)
+
+  day_series_groups, _ := ActivityRecordsAggregate(records, "day", series_key)
+
+  day_indices := make(map [string] int)
+  if len(day_series_groups) > 0 {
+    first_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_series_groups[0].Keys[0], SttLocation())
+    final_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_series_groups[len(day_series_groups)-1].Keys[0], SttLocation())
+    for day := first_day; ! day.After(final_day); day = day.AddDate(0, 0, 1) {
+      day_indices[day.Format(STT_DATE_LAYOUT)] = len(days)
+      days = append(days, day)
+    }
+  }
+
+  series_indices := make(map [string] int, len(series_groups))
+  daily           = make([][] float64, len(series_groups))
+  for series_i, series := range series_groups {
+    series_indices[series.Keys[0]] = series_i
+    daily[series_i] = make([] float64, len(days))
+  }
+  for _, group := range day_series_groups {
+    daily[series_indices[group.Keys[1]]][day_indices[group.Keys[0]]] = float64(group.Minutes)
+  }
+  return series_groups, days, daily
+}
+
+
+func activityRecordsBarChartSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot a stacked bar per day, from the first record's day to the final
+    record's, with a segment per activity (or category or tag, per the
+    Group_by option), the largest series at the bottom.
+  */
+
+  options = svgChartOptions(options, "640", "320")
+  series_key := chartSeriesKey(options)
+
+  // Series, largest first, and minutes per day per series
+
+  series_groups, days, daily := activityRecordsDailySeries(records, series_key)
+
+  var max_minutes uint = 0
+  for day_i := range days {
+    var minutes uint = 0
+    for series_i := range series_groups {
+      minutes += uint(daily[series_i][day_i])
+    }
+    if minutes > max_minutes {
+      max_minutes = minutes
+    }
+  }
+
+  // Geometry, in viewBox units: the plot, with axis labels to its left and
+  // below, and the legend to its right
+
+  const plot_left     float64 = 40
+  const plot_top      float64 = 20
+  const plot_width    float64 = 460
+  const plot_height   float64 = 240
+  const legend_left   float64 = plot_left + plot_width + 20
+  const legend_width  float64 = 140
+  const legend_row    float64 = 16
+
+  view_height := math.Max(plot_top + plot_height + 30, plot_top + float64(len(series_groups)) * legend_row)
+
+  ticks     := chartNiceTicks(float64(max_minutes) / 60, 5)
+  max_hours := ticks[len(ticks)-1]
+  y := func (minutes float64) float64 {
+    return plot_top + plot_height - plot_height * (minutes / 60) / max_hours
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, legend_left + legend_width, view_height },
+    "Time per day by " + series_key, chartDescribeRecords(records, series_groups),
+    "    rect.segment:hover { filter: brightness(1.1); stroke: #8888; stroke-width: 1; }\n" +
+    "    line.grid { stroke: #8884; stroke-width: 0.5; }\n" +
+    "    line.axis { stroke: #888; stroke-width: 1; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n" +
+    "    text.total { font-size: 8px; fill: #444; }\n",
+  )
+
+  // Gridlines and y-axis labels
+
+  for _, tick := range ticks {
+    tick_y := y(tick * 60)
+    svg.Add("line").Attr("class", "grid").
+      Num("x1", plot_left).Num("y1", tick_y).Num("x2", plot_left + plot_width).Num("y2", tick_y)
+    svg.Add("text").
+      Num("x", plot_left - 4).Num("y", tick_y).Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+      Text(chartFormatHours(tick))
+  }
+
+  // Bars, with a total above each, and every few days' labels below
+
+  if len(days) > 0 {
+    slot_width  := plot_width / float64(len(days))
+    bar_width   := slot_width * 0.8
+    label_every := int(math.Ceil(float64(len(days)) / 12))
+
+    for day_i, day := range days {
+      date_str := day.Format(STT_DATE_LAYOUT)
+      bar_x    := plot_left + float64(day_i) * slot_width + (slot_width - bar_width) / 2
+      var stacked uint = 0
+
+      for series_i, series := range series_groups {
+        minutes := uint(daily[series_i][day_i])
+        if minutes == 0 { continue }
+
+        segment_top := y(float64(stacked + minutes))
+        svg.Add("rect").Attr("class", "segment").
+          Num("x", bar_x).Num("y", segment_top).Num("width", bar_width).Num("height", y(float64(stacked)) - segment_top).
+          Attr("fill", chartColor(options, series.Keys[0])).
+          Title(fmt.Sprintf("%s, %s: %s", series.Keys[0], date_str, minutesFormatDuration(minutes)))
+        stacked += minutes
+      }
+
+      if stacked > 0 {
+        svg.Add("text").Attr("class", "total").
+          Num("x", bar_x + bar_width/2).Num("y", y(float64(stacked)) - 3).Attr("text-anchor", "middle").
+          Text(minutesFormatDuration(stacked))
+      }
+
+      if day_i % label_every == 0 {
+        svg.Add("text").
+          Num("x", bar_x + bar_width/2).Num("y", plot_top + plot_height + 12).Attr("text-anchor", "middle").
+          Text(day.Format("Mon 2"))
+      }
+    }
+  }
+
+  // Axes
+
+  svg.Add("line").Attr("class", "axis").
+    Num("x1", plot_left).Num("y1", plot_top).Num("x2", plot_left).Num("y2", plot_top + plot_height)
+  svg.Add("line").Attr("class", "axis").
+    Num("x1", plot_left).Num("y1", plot_top + plot_height).Num("x2", plot_left + plot_width).Num("y2", plot_top + plot_height)
+
+  // Legend
+
+  for series_i, series := range series_groups {
+    row_y := plot_top + float64(series_i) * legend_row
+    svg.Add("rect").
+      Num("x", legend_left).Num("y", row_y).Num("width", 10).Num("height", 10).
+      Attr("fill", chartColor(options, series.Keys[0]))
+    svg.Add("text").
+      Num("x", legend_left + 14).Num("y", row_y + 5).Attr("dominant-baseline", "middle").
+      Text(fmt.Sprintf("%s (%s)", series.Keys[0], minutesFormatDuration(series.Minutes)))
+  }
+
+  return svg
+}
+
+
+func activityRecordsBarChartTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per bar's day, with each series' time and the day's total, and a
+    row of each series' total.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  series_key := chartSeriesKey(options)
+  series_groups, days, daily := activityRecordsDailySeries(records, series_key)
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Time per day by " + series_key),
+    Columns: [] string { "Day" },
+    Footer:  [] string { "Total" },
+  }
+  var total_minutes uint = 0
+  for _, series := range series_groups {
+    table.Columns = append(table.Columns, series.Keys[0])
+    table.Footer  = append(table.Footer, minutesFormatDuration(series.Minutes))
+    total_minutes += series.Minutes
+  }
+  table.Columns = append(table.Columns, "Total")
+  table.Footer  = append(table.Footer, minutesFormatDuration(total_minutes))
+
+  for day_i, day := range days {
+    row := [] string { day.Format(STT_DATE_LAYOUT) }
+    day_minutes := 0.0
+    for series_i := range series_groups {
+      row = append(row, chartTableMinutes(daily[series_i][day_i]))
+      day_minutes += daily[series_i][day_i]
+    }
+    table.Rows = append(table.Rows, append(row, chartTableMinutes(day_minutes)))
+  }
+  return table
+}
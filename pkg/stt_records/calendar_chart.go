@@ -0,0 +1,191 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "time"
+)
+
+
+var CALENDAR_CHART_LEVELS [] string = [] string {
+  "#ebedf0",
+  "#c6e3f5",
+  "#7fbde6",
+  "#3a8fc9",
+  "#1b5f91",
+}
+
+
+func activityRecordsCalendarSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot a contribution-graph style calendar of the year up to the final
+    record's day: a column per week, a row per weekday, and a cell per day
+    shaded by its total minutes.
+  */
+
+  options = svgChartOptions(options, "720", "140")
+
+  // Daily totals, and the year of days to plot
+
+  day_groups, _ := ActivityRecordsAggregate(records, "day")
+  day_minutes   := make(map [string] uint, len(day_groups))
+  var max_minutes uint = 0
+
+  for _, group := range day_groups {
+    day_minutes[group.Keys[0]] = group.Minutes
+    if group.Minutes > max_minutes {
+      max_minutes = group.Minutes
+    }
+  }
+
+  final_day := DayStart(time.Now().In(SttLocation()))
+  if len(day_groups) > 0 {
+    final_day, _ = time.ParseInLocation(STT_DATE_LAYOUT, day_groups[len(day_groups)-1].Keys[0], SttLocation())
+  }
+  first_day  := final_day.AddDate(-1, 0, 1)
+  first_week := WeekStart(first_day)
+  weeks      := int(math.Round(final_day.Sub(first_week).Hours() / 24)) / 7 + 1
+
+  // Geometry, in viewBox units
+
+  const cell_size    float64 = 11
+  const cell_gap     float64 = 2
+  const label_width  float64 = 28
+  const label_height float64 = 16
+  const scale_height float64 = 20
+
+  cell_step   := cell_size + cell_gap
+  grid_width  := float64(weeks) * cell_step
+  grid_height := 7 * cell_step
+
+  // The description names the busiest day
+
+  description := "No records."
+  var total_minutes uint = 0
+  busy_days := 0
+  for _, group := range day_groups {
+    total_minutes += group.Minutes
+    if group.Minutes > 0 {
+      busy_days++
+    }
+  }
+  if total_minutes > 0 {
+    busiest := day_groups[0]
+    for _, group := range day_groups {
+      if group.Minutes > busiest.Minutes {
+        busiest = group
+      }
+    }
+    days_str := "days"
+    if busy_days == 1 {
+      days_str = "day"
+    }
+    description = fmt.Sprintf(
+      "%s over %d %s %s. The busiest day is %s, with %s.",
+      minutesFormatDuration(total_minutes), busy_days, days_str, chartDescribeSpan(records),
+      busiest.Keys[0], minutesFormatDuration(busiest.Minutes),
+    )
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, label_width + grid_width, label_height + grid_height + scale_height },
+    "Time per day", description,
+    "    rect.day { rx: 2; ry: 2; }\n" +
+    "    rect.day:hover { stroke: #555; stroke-width: 1; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n",
+  )
+
+  // Weekday labels, every other row
+
+  for weekday_i := 0; weekday_i < 7; weekday_i += 2 {
+    weekday := time.Weekday((weekday_i + int(STT_WEEK_START)) % 7)
+    svg.Add("text").
+      Num("x", label_width - 4).Num("y", label_height + float64(weekday_i) * cell_step + cell_size/2).
+      Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+      Text(weekday.String()[:3])
+  }
+
+  // Day cells, with month labels above the first week of each month
+
+  for week_i := 0; week_i < weeks; week_i++ {
+    week_start := first_week.AddDate(0, 0, 7 * week_i)
+    column_x   := label_width + float64(week_i) * cell_step
+
+    for weekday_i := 0; weekday_i < 7; weekday_i++ {
+      day := week_start.AddDate(0, 0, weekday_i)
+      if day.Before(first_day) || day.After(final_day) { continue }
+
+      // The first, partial month is only labelled when there's room for it
+      if day.Day() == 1 || (day.Equal(first_day) && first_day.Day() <= 15) {
+        svg.Add("text").Num("x", column_x).Num("y", label_height - 5).Text(day.Month().String()[:3])
+      }
+
+      date_str := day.Format(STT_DATE_LAYOUT)
+      minutes  := day_minutes[date_str]
+      level    := 0
+      if minutes > 0 && max_minutes > 0 {
+        level = int(math.Ceil(float64(len(CALENDAR_CHART_LEVELS) - 1) * float64(minutes) / float64(max_minutes)))
+      }
+
+      svg.Add("rect").Attr("class", "day").
+        Num("x", column_x).Num("y", label_height + float64(weekday_i) * cell_step).
+        Num("width", cell_size).Num("height", cell_size).
+        Attr("fill", CALENDAR_CHART_LEVELS[level]).
+        Title(fmt.Sprintf("%s %s: %s", day.Weekday().String()[:3], date_str, minutesFormatDuration(minutes)))
+    }
+  }
+
+  // Scale, from no time to the busiest day
+
+  scale_y := label_height + grid_height + 6
+  scale_x := label_width + grid_width - float64(len(CALENDAR_CHART_LEVELS)) * cell_step - 40
+
+  svg.Add("text").
+    Num("x", scale_x - 4).Num("y", scale_y + cell_size/2).Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+    Text("0m")
+  for level, fill := range CALENDAR_CHART_LEVELS {
+    svg.Add("rect").Attr("class", "day").
+      Num("x", scale_x + float64(level) * cell_step).Num("y", scale_y).Num("width", cell_size).Num("height", cell_size).
+      Attr("fill", fill)
+  }
+  svg.Add("text").
+    Num("x", scale_x + float64(len(CALENDAR_CHART_LEVELS)) * cell_step + 2).Num("y", scale_y + cell_size/2).
+    Attr("dominant-baseline", "middle").
+    Text(minutesFormatDuration(max_minutes))
+
+  return svg
+}
+
+
+func activityRecordsCalendarTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per day of the calendar's year that has any time, with its total.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  day_groups, _ := ActivityRecordsAggregate(records, "day")
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Time per day"),
+    Columns: [] string { "Day", "Time" },
+  }
+  if len(day_groups) == 0 {
+    table.Footer = [] string { "Total", minutesFormatDuration(0) }
+    return table
+  }
+
+  final_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_groups[len(day_groups)-1].Keys[0], SttLocation())
+  first_day    := final_day.AddDate(-1, 0, 1).Format(STT_DATE_LAYOUT)
+  var total_minutes uint = 0
+  for _, group := range day_groups {
+    if group.Keys[0] < first_day { continue }
+    table.Rows     = append(table.Rows, [] string { group.Keys[0], minutesFormatDuration(group.Minutes) })
+    total_minutes += group.Minutes
+  }
+  table.Footer = [] string { "Total", minutesFormatDuration(total_minutes) }
+  return table
+}
@@ -0,0 +1,106 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "io"
+  "sort"
+  "strings"
+)
+
+
+//
+// Charts by name. Each kind of chart renders records to a writer, so that
+// handlers can stream them, and tabulates the data it's drawn from. It's
+// registered under the name requests can ask for it by.
+//
+
+
+type Chart interface {
+  Render (writer io.Writer, records [] ActivityRecord, options * ActivityRecordChartOptions) error
+  Table  (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable
+}
+
+
+// A Chart of a function that builds its SVG, which it renders by writing
+// the document out element by element, as the activityRecords...Svg
+// functions do, and one that tabulates its data
+type SvgChart struct {
+  Build    func (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement;
+  Tabulate func (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable;
+}
+
+func (chart SvgChart) Render (writer io.Writer, records [] ActivityRecord, options * ActivityRecordChartOptions) error {
+  _, err := chart.Build(records, options).WriteTo(writer)
+  return err
+}
+
+func (chart SvgChart) Table (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  return chart.Tabulate(records, options)
+}
+
+
+var chart_registry map [string] Chart = map [string] Chart {}
+
+
+func RegisterChart (name string, chart Chart) {
+  chart_registry[name] = chart
+}
+
+
+func LookupChart (name string) (Chart, error) {
+  chart, found := chart_registry[name]
+  if ! found {
+    return nil, fmt.Errorf("unknown chart type \"%s\", expected one of %s", name, strings.Join(ChartNames(), ", "))
+  }
+  return chart, nil
+}
+
+
+func RenderChart (writer io.Writer, name string, records [] ActivityRecord, options * ActivityRecordChartOptions) error {
+  chart, err := LookupChart(name)
+  if err != nil { return err }
+  return chart.Render(writer, records, options)
+}
+
+
+func ChartNames () [] string {
+  names := make([] string, 0, len(chart_registry))
+  for name := range chart_registry {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+  return names
+}
+
+
+func chartTransitions (records [] ActivityRecord, options * ActivityRecordChartOptions) ActivityTransitions {
+  max_gap := TRANSITIONS_MAX_GAP
+  if options != nil && options.Max_gap != nil {
+    max_gap = *options.Max_gap
+  }
+  return ActivityRecordsTransitions(records, max_gap)
+}
+
+
+func init () {
+  RegisterChart("pie",      SvgChart { activityRecordsPieSvg,       activityRecordsPieTable })
+  RegisterChart("bars",     SvgChart { activityRecordsBarChartSvg,  activityRecordsBarChartTable })
+  RegisterChart("lines",    SvgChart { activityRecordsLineChartSvg, activityRecordsLineChartTable })
+  RegisterChart("timeline", SvgChart { activityRecordsTimelineSvg,  activityRecordsTimelineTable })
+  RegisterChart("clock",    SvgChart { activityRecordsClockSvg,     activityRecordsClockTable })
+  RegisterChart("calendar", SvgChart { activityRecordsCalendarSvg,  activityRecordsCalendarTable })
+  RegisterChart("heatmap",  SvgChart { activityRecordsHeatmapSvg,   activityRecordsHeatmapTable })
+  RegisterChart("sunburst", SvgChart { activityRecordsSunburstSvg,  activityRecordsHierarchyTable })
+  RegisterChart("treemap",  SvgChart { activityRecordsTreemapSvg,   activityRecordsHierarchyTable })
+  RegisterChart("transitions", SvgChart {
+    func (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+      transitions := chartTransitions(records, options)
+      return activityTransitionsSankeySvg(&transitions, options)
+    },
+    func (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+      transitions := chartTransitions(records, options)
+      return activityTransitionsTable(&transitions, options)
+    },
+  })
+}
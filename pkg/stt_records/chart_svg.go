@@ -0,0 +1,337 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "strconv"
+  "strings"
+
+  "golang.org/x/image/font"
+  "golang.org/x/image/font/gofont/goregular"
+  "golang.org/x/image/font/opentype"
+)
+
+
+//
+// Helpers shared by the SVG chart renderers.
+//
+
+
+func svgChartRoot (options * ActivityRecordChartOptions, view_box [4] float64, title, description, style string) * SvgElement {
+  /*
+    The root element with the chart's width and height, those it has, its
+    role and accessible name (its Title option, or the given title), its
+    title and description, and a style element. Without a height, it follows
+    from the width and the view box's aspect ratio.
+  */
+
+  title = chartTitle(options, title)
+
+  root := SvgRoot(view_box[0], view_box[1], view_box[2], view_box[3])
+  if options.Width != "" {
+    root.Attr("width", options.Width)
+  }
+  if options.Height != "" {
+    root.Attr("height", options.Height)
+  }
+  root.Attr("role", "img").Attr("aria-label", title)
+  root.Add("title").Text(title)
+  root.Add("desc").Text(description)
+  root.Add("style").Text("\n" + style + "  ")
+  return root
+}
+
+
+func svgChartOptions (options * ActivityRecordChartOptions, width, height string) * ActivityRecordChartOptions {
+  /*
+    The options to render with, defaulting to a chart of the given size if
+    they don't give a width or height.
+  */
+  if options == nil {
+    return & ActivityRecordChartOptions { Width: width, Height: height }
+  }
+  if options.Width == "" && options.Height == "" {
+    sized := *options
+    sized.Width, sized.Height = width, height
+    return &sized
+  }
+  return options
+}
+
+
+const CHART_SIZE_MAX int = 4096
+
+
+func ParseChartSize (size_str string) (width, height string, err error) {
+  /*
+    Parse a chart size, as "WIDTHxHEIGHT" or just "WIDTH" (the height then
+    following from the chart's aspect ratio), in pixels.
+  */
+
+  width_str, height_str, has_height := strings.Cut(strings.ToLower(size_str), "x")
+  parse := func (dimension_str string) (string, error) {
+    dimension, err := strconv.Atoi(strings.TrimSpace(dimension_str))
+    if err != nil || dimension < 1 || dimension > CHART_SIZE_MAX {
+      return "", fmt.Errorf("invalid size \"%s\", expected WIDTHxHEIGHT in pixels up to %d", size_str, CHART_SIZE_MAX)
+    }
+    return strconv.Itoa(dimension), nil
+  }
+
+  width, err = parse(width_str)
+  if err != nil { return "", "", err }
+  if has_height {
+    height, err = parse(height_str)
+    if err != nil { return "", "", err }
+  }
+  return width, height, nil
+}
+
+
+func chartSeriesKey (options * ActivityRecordChartOptions) string {
+  /*
+    The aggregation key of the chart's series, per its Group_by option.
+  */
+  switch options.Group_by {
+  case "category", "tag":
+    return options.Group_by
+  }
+  return "activity"
+}
+
+
+func ValidateGroupBy (chart_name, group_by string) error {
+  /*
+    Whether a chart (by its registered name) can split its series by
+    group_by: every chart takes activity, category or tag, and the timeline
+    also takes day.
+  */
+
+  switch group_by {
+  case "", "activity", "category", "tag":
+    return nil
+  case "day":
+    if chart_name == "timeline" {
+      return nil
+    }
+  }
+
+  expected := "activity, category or tag"
+  if chart_name == "timeline" {
+    expected = "activity, category, tag or day"
+  }
+  return fmt.Errorf("unknown grouping \"%s\" for the %s chart, expected %s", group_by, chart_name, expected)
+}
+
+
+//
+// Accessible names and descriptions
+//
+
+
+// The most groups a chart's description names
+const CHART_DESCRIBE_MAX int = 8
+
+
+func chartTitle (options * ActivityRecordChartOptions, title string) string {
+  if options.Title != "" {
+    return options.Title
+  }
+  return title
+}
+
+
+func chartDescribeSpan (records [] ActivityRecord) string {
+  /*
+    The days records span, as "on 2006-01-02" or "from 2006-01-02 to
+    2006-01-08".
+  */
+
+  if len(records) == 0 { return "" }
+
+  first_day, final_day := records[0].DayStart(), records[0].DayStart()
+  for record_i := range records {
+    day := records[record_i].DayStart()
+    if day.Before(first_day) {
+      first_day = day
+    }
+    if day.After(final_day) {
+      final_day = day
+    }
+  }
+
+  if first_day.Equal(final_day) {
+    return "on " + first_day.Format(STT_DATE_LAYOUT)
+  }
+  return "from " + first_day.Format(STT_DATE_LAYOUT) + " to " + final_day.Format(STT_DATE_LAYOUT)
+}
+
+
+func chartDescribeGroups (groups [] AggregateGroup) string {
+  /*
+    Groups' names, times and shares of their total, largest first, as
+    "Email 21h46m (50.8%), Meeting 10h57m (25.6%)", naming up to
+    CHART_DESCRIBE_MAX of them.
+  */
+
+  sorted := append([] AggregateGroup {}, groups...)
+  AggregateGroupsSortBySum(sorted)
+
+  var total_minutes uint = 0
+  for _, group := range sorted {
+    total_minutes += group.Minutes
+  }
+
+  descriptions := make([] string, 0, CHART_DESCRIBE_MAX + 1)
+  for group_i, group := range sorted {
+    if group_i == CHART_DESCRIBE_MAX {
+      descriptions = append(descriptions, fmt.Sprintf("and %d more", len(sorted) - group_i))
+      break
+    }
+    descriptions = append(descriptions, fmt.Sprintf(
+      "%s %s (%.1f%%)",
+      strings.Join(group.Keys, " › "), minutesFormatDuration(group.Minutes), chartPercent(group.Minutes, total_minutes),
+    ))
+  }
+  return strings.Join(descriptions, ", ")
+}
+
+
+func chartDescribeRecords (records [] ActivityRecord, groups [] AggregateGroup) string {
+  /*
+    A description of records' total time and span, and of its groups, as
+    "52h27m from 2026-10-05 to 2026-10-11: Email 21h46m (50.8%), ...".
+  */
+
+  var total_minutes uint = 0
+  for _, group := range groups {
+    total_minutes += group.Minutes
+  }
+  if total_minutes == 0 {
+    return "No records."
+  }
+  return fmt.Sprintf(
+    "%s %s: %s.",
+    minutesFormatDuration(total_minutes), chartDescribeSpan(records), chartDescribeGroups(groups),
+  )
+}
+
+
+func chartNiceTicks (max_value float64, tick_count int) (ticks [] float64) {
+  /*
+    Evenly spaced ticks from zero to at least max_value, at about tick_count
+    intervals of 1, 2 or 5 times a power of ten.
+  */
+
+  if max_value <= 0 || tick_count < 1 {
+    return [] float64 { 0, 1 }
+  }
+
+  raw_step  := max_value / float64(tick_count)
+  magnitude := math.Pow(10, math.Floor(math.Log10(raw_step)))
+  step      := 10 * magnitude
+  for _, multiple := range [] float64 { 1, 2, 5 } {
+    if multiple * magnitude >= raw_step {
+      step = multiple * magnitude
+      break
+    }
+  }
+
+  for tick := 0.0; ; tick += step {
+    ticks = append(ticks, tick)
+    if tick >= max_value { break }
+  }
+  return ticks
+}
+
+
+func chartFormatHours (hours float64) string {
+  /*
+    An axis label for a number of hours.
+  */
+  if hours == math.Trunc(hours) {
+    return fmt.Sprintf("%gh", hours)
+  }
+  return fmt.Sprintf("%gh", math.Round(hours * 100) / 100)
+}
+
+
+// The advances of printable ASCII characters in the Go font, in ems, and of
+// the widest of them, for anything else
+var chart_text_advances, chart_text_advance_max = chartTextAdvances()
+
+
+func chartTextAdvances () (advances [95] float64, advance_max float64) {
+  const em float64 = 1000
+
+  parsed, err := opentype.Parse(goregular.TTF)
+  if err != nil { return advances, 0.6 }
+  face, err := opentype.NewFace(parsed, & opentype.FaceOptions { Size: em, DPI: 72, Hinting: font.HintingNone })
+  if err != nil { return advances, 0.6 }
+  defer face.Close()
+
+  for char_i := range advances {
+    advance, _ := face.GlyphAdvance(rune(' ' + char_i))
+    advances[char_i] = float64(advance) / 64 / em
+    advance_max      = math.Max(advance_max, advances[char_i])
+  }
+  return advances, advance_max
+}
+
+
+func chartTextWidth (text string, font_size float64) float64 {
+  /*
+    The width of text at a font size, in the same units, as set in the Go
+    font. That's the font PNGs are drawn in, and it's a little wider than
+    most sans-serif fonts, so text laid out by it fits in browsers too.
+  */
+  width := 0.0
+  for _, char := range text {
+    if char >= ' ' && char <= '~' {
+      width += chart_text_advances[char - ' ']
+    } else {
+      width += chart_text_advance_max
+    }
+  }
+  return width * font_size
+}
+
+
+func svgArcPath (start_t, end_t, inner_r, outer_r float64) * SvgPath {
+  /*
+    The path of a ring segment around the origin, clockwise from angle start_t
+    to end_t (in radians, from the positive x axis), between radii inner_r and
+    outer_r. With an inner_r of zero, it's a pie slice.
+  */
+
+  path := & SvgPath {}
+
+  // A full circle's arc would start and end at the same point once rounded,
+  // and not draw; go round in two halves instead, and cut a ring's hole out
+  // with the inner circle drawn the other way
+  if end_t - start_t >= 2 * math.Pi {
+    mid_t := start_t + math.Pi
+    path.MoveTo(outer_r * math.Cos(start_t), outer_r * math.Sin(start_t))
+    path.ArcTo(outer_r, outer_r, 0, false, true, outer_r * math.Cos(mid_t), outer_r * math.Sin(mid_t))
+    path.ArcTo(outer_r, outer_r, 0, false, true, outer_r * math.Cos(start_t), outer_r * math.Sin(start_t))
+    path.Close()
+    if inner_r > 0 {
+      path.MoveTo(inner_r * math.Cos(start_t), inner_r * math.Sin(start_t))
+      path.ArcTo(inner_r, inner_r, 0, false, false, inner_r * math.Cos(mid_t), inner_r * math.Sin(mid_t))
+      path.ArcTo(inner_r, inner_r, 0, false, false, inner_r * math.Cos(start_t), inner_r * math.Sin(start_t))
+      path.Close()
+    }
+    return path
+  }
+  large_arc := end_t - start_t > math.Pi
+
+  path.MoveTo(outer_r * math.Cos(start_t), outer_r * math.Sin(start_t))
+  path.ArcTo(outer_r, outer_r, 0, large_arc, true, outer_r * math.Cos(end_t), outer_r * math.Sin(end_t))
+  if inner_r > 0 {
+    path.LineTo(inner_r * math.Cos(end_t), inner_r * math.Sin(end_t))
+    path.ArcTo(inner_r, inner_r, 0, large_arc, false, inner_r * math.Cos(start_t), inner_r * math.Sin(start_t))
+  } else {
+    path.LineTo(0, 0)
+  }
+  return path.Close()
+}
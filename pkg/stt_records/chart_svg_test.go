@@ -0,0 +1,108 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "encoding/xml"
+  "io"
+  "strings"
+  "testing"
+)
+
+
+// Render a registered chart, and check that it's well-formed XML with no
+// NaN or infinite numbers in it
+func testChartSvg (t * testing.T, name string, records [] ActivityRecord, options * ActivityRecordChartOptions) string {
+  t.Helper()
+
+  chart, err := LookupChart(name)
+  if err != nil { t.Fatal(err) }
+
+  var svg bytes.Buffer
+  if err = chart.Render(&svg, records, options); err != nil {
+    t.Fatalf("%s: %v", name, err)
+  }
+
+  decoder := xml.NewDecoder(bytes.NewReader(svg.Bytes()))
+  for {
+    _, err := decoder.Token()
+    if err == io.EOF { break }
+    if err != nil {
+      t.Fatalf("%s: %v in\n%s", name, err, svg.String())
+    }
+  }
+
+  for _, bad := range [] string { "NaN", "Inf" } {
+    if strings.Contains(svg.String(), bad) {
+      t.Errorf("%s: %s in\n%s", name, bad, svg.String())
+    }
+  }
+
+  return svg.String()
+}
+
+
+func TestSvgArcPath (t * testing.T) {
+  tests := [] struct {
+    start_t, end_t   float64;
+    inner_r, outer_r float64;
+    want             string;
+  } {
+    // A quarter pie slice, and the same as a ring segment
+    { 0, 0.5 * 3.141592653589793, 0, 1,   "M 1 0 A 1 1 0 0 1 0 1 L 0 0 Z" },
+    { 0, 0.5 * 3.141592653589793, 0.5, 1, "M 1 0 A 1 1 0 0 1 0 1 L 0 0.5 A 0.5 0.5 0 0 0 0.5 0 Z" },
+    // Full circles go round in two halves, the hole the other way
+    { 0, 2 * 3.141592653589793, 0, 1,     "M 1 0 A 1 1 0 0 1 -1 0 A 1 1 0 0 1 1 0 Z" },
+    { 0, 2 * 3.141592653589793, 0.5, 1,   "M 1 0 A 1 1 0 0 1 -1 0 A 1 1 0 0 1 1 0 Z M 0.5 0 A 0.5 0.5 0 0 0 -0.5 0 A 0.5 0.5 0 0 0 0.5 0 Z" },
+    { 0, 3 * 3.141592653589793, 0, 1,     "M 1 0 A 1 1 0 0 1 -1 0 A 1 1 0 0 1 1 0 Z" },
+  }
+
+  for _, test := range tests {
+    got := svgArcPath(test.start_t, test.end_t, test.inner_r, test.outer_r).Format(4)
+    if got != test.want {
+      t.Errorf("%v..%v, %v..%v: got %q, want %q", test.start_t, test.end_t, test.inner_r, test.outer_r, got, test.want)
+    }
+  }
+}
+
+
+func TestChartsFullCircle (t * testing.T) {
+  // One activity fills the whole pie, the sunburst's rings, and (lasting a
+  // day) the clock's dial; each must still draw
+  records := [] ActivityRecord {
+    testRecord("Sleep", "2026-03-02 00:00", "2026-03-03 00:00"),
+  }
+  records[0].Categories = [] string { "Health" }
+
+  for _, name := range [] string { "pie", "sunburst", "clock" } {
+    svg := testChartSvg(t, name, records, & ActivityRecordChartOptions { Group_by: "category" })
+    if ! strings.Contains(svg, " A ") {
+      t.Errorf("%s: no arcs in\n%s", name, svg)
+    }
+  }
+}
+
+
+func TestChartsEscapeNames (t * testing.T) {
+  // Names and comments with markup in them come out as text, escaped once,
+  // on every chart
+  records := [] ActivityRecord {
+    testRecord(`<b>Email</b> & "chat"`, "2026-03-02 09:00", "2026-03-02 10:00"),
+    testRecord("Coding",                "2026-03-02 10:00", "2026-03-02 12:00"),
+  }
+  records[0].Categories  = [] string { "R&D" }
+  records[0].Record_tags = "<tag>"
+  records[0].Comment     = `</title><script>alert("hi")</script>`
+  records[1].Categories  = [] string { "R&D" }
+
+  for _, name := range ChartNames() {
+    for _, group_by := range [] string { "activity", "category", "tag" } {
+      svg := testChartSvg(t, name, records, & ActivityRecordChartOptions { Group_by: group_by })
+      for _, bad := range [] string { "<b>", "<script>", "<tag>", "&amp;amp;", "&amp;lt;", "&amp;#34;" } {
+        if strings.Contains(svg, bad) {
+          t.Errorf("%s by %s: %s in\n%s", name, group_by, bad, svg)
+        }
+      }
+    }
+  }
+}
@@ -0,0 +1,97 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "html"
+  "io"
+  "math"
+  "strings"
+)
+
+
+//
+// The data behind each chart, as a table, for screen readers and anyone else
+// who'd rather read the numbers. Each chart's table comes from the same data
+// it's drawn from: the pie's slices, the bars' days, the heatmap's hours of
+// each weekday, and so on.
+//
+
+
+type ChartTable struct {
+  /*
+    Columns are the headings, the first of them over the rows' headings;
+    each row's first cell is its heading. The Footer is a row of totals, if
+    the table has one.
+  */
+  Caption string;
+  Columns [] string;
+  Rows    [][] string;
+  Footer  [] string;
+}
+
+
+func (table * ChartTable) WriteHTML (writer io.Writer) error {
+  /*
+    Write the table as an HTML table of class "chart-data".
+  */
+
+  var html_table strings.Builder
+  html_table.WriteString("<table class=\"chart-data\">\n")
+  if table.Caption != "" {
+    fmt.Fprintf(&html_table, "  <caption>%s</caption>\n", html.EscapeString(table.Caption))
+  }
+
+  html_table.WriteString("  <thead><tr>")
+  for _, column := range table.Columns {
+    fmt.Fprintf(&html_table, "<th scope=\"col\">%s</th>", html.EscapeString(column))
+  }
+  html_table.WriteString("</tr></thead>\n")
+
+  writeRow := func (row [] string) {
+    for cell_i, cell := range row {
+      if cell_i == 0 {
+        fmt.Fprintf(&html_table, "<th scope=\"row\">%s</th>", html.EscapeString(cell))
+      } else {
+        fmt.Fprintf(&html_table, "<td>%s</td>", html.EscapeString(cell))
+      }
+    }
+  }
+
+  html_table.WriteString("  <tbody>\n")
+  for _, row := range table.Rows {
+    html_table.WriteString("    <tr>")
+    writeRow(row)
+    html_table.WriteString("</tr>\n")
+  }
+  html_table.WriteString("  </tbody>\n")
+
+  if table.Footer != nil {
+    html_table.WriteString("  <tfoot><tr>")
+    writeRow(table.Footer)
+    html_table.WriteString("</tr></tfoot>\n")
+  }
+  html_table.WriteString("</table>\n")
+
+  _, err := io.WriteString(writer, html_table.String())
+  return err
+}
+
+
+func chartTableHeading (key string) string {
+  /*
+    A column heading for an aggregation key, as "Activity" for "activity".
+  */
+  if key == "" { return "" }
+  return strings.ToUpper(key[:1]) + key[1:]
+}
+
+
+func chartTableMinutes (minutes float64) string {
+  return minutesFormatDuration(uint(math.Round(minutes)))
+}
+
+
+func chartTablePercent (percent float64) string {
+  return fmt.Sprintf("%.1f%%", percent)
+}
@@ -0,0 +1,258 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "encoding/xml"
+  "io"
+  "strings"
+  "testing"
+)
+
+
+func TestChartTableWriteHTML (t * testing.T) {
+  tests := [] struct {
+    name  string;
+    table ChartTable;
+    want  [] string;
+    not   [] string;
+  } {
+    {
+      "headings, cells and footer",
+      ChartTable {
+        Caption: "Time by activity",
+        Columns: [] string { "Activity", "Time" },
+        Rows:    [][] string { { "Email", "1h0m" } },
+        Footer:  [] string { "Total", "1h0m" },
+      },
+      [] string {
+        "<caption>Time by activity</caption>",
+        `<th scope="col">Activity</th><th scope="col">Time</th>`,
+        `<tr><th scope="row">Email</th><td>1h0m</td></tr>`,
+        `<tfoot><tr><th scope="row">Total</th><td>1h0m</td></tr></tfoot>`,
+      },
+      nil,
+    },
+    {
+      "no caption or footer",
+      ChartTable { Columns: [] string { "Day" } },
+      [] string { "<tbody>\n  </tbody>" },
+      [] string { "<caption>", "<tfoot>" },
+    },
+    {
+      "escaped once",
+      ChartTable {
+        Caption: "R&D",
+        Columns: [] string { "<b>" },
+        Rows:    [][] string { { `"chat"`, "<script>" } },
+      },
+      [] string { "R&amp;D", "&lt;b&gt;", "&#34;chat&#34;", "&lt;script&gt;" },
+      [] string { "<b>", "<script>", "&amp;amp;" },
+    },
+  }
+
+  for _, test := range tests {
+    var html bytes.Buffer
+    if err := test.table.WriteHTML(&html); err != nil {
+      t.Fatalf("%s: %v", test.name, err)
+    }
+    for _, want := range test.want {
+      if ! strings.Contains(html.String(), want) {
+        t.Errorf("%s: no %q in\n%s", test.name, want, html.String())
+      }
+    }
+    for _, not := range test.not {
+      if strings.Contains(html.String(), not) {
+        t.Errorf("%s: %q in\n%s", test.name, not, html.String())
+      }
+    }
+  }
+}
+
+
+func testChartTableRow (table * ChartTable, heading string) [] string {
+  for _, row := range table.Rows {
+    if row[0] == heading {
+      return row
+    }
+  }
+  return nil
+}
+
+
+func TestChartsTables (t * testing.T) {
+  // Each chart's table has its own data: slices, days, hours, flows
+  records := [] ActivityRecord {
+    testRecord("Email",  "2026-03-02 09:00", "2026-03-02 10:00"),
+    testRecord("Coding", "2026-03-02 10:00", "2026-03-02 13:00"),
+    testRecord("Email",  "2026-03-04 09:00", "2026-03-04 09:30"),
+  }
+  for record_i := range records {
+    records[record_i].Categories = [] string { "Work" }
+  }
+
+  tests := [] struct {
+    chart   string;
+    options ActivityRecordChartOptions;
+    caption string;
+    columns [] string;
+    rows    [][] string;
+    footer  [] string;
+  } {
+    {
+      "pie", ActivityRecordChartOptions {},
+      "Time by activity",
+      [] string { "Activity", "Time", "Share" },
+      [][] string { { "Coding", "3h0m", "66.7%" }, { "Email", "1h30m", "33.3%" } },
+      [] string { "Total", "4h30m", "" },
+    },
+    {
+      "pie", ActivityRecordChartOptions {
+        Targets:         map [string] float64 { "Email": 1, "Reading": 1 },
+        Compare_records: records[:1],
+        Compare_label:   "Last week",
+      },
+      "Time by activity",
+      [] string { "Activity", "Time", "Share", "Last week time", "Last week share", "Target share" },
+      [][] string {
+        { "Coding",  "3h0m",  "66.7%", "",     "",       "" },
+        { "Email",   "1h30m", "33.3%", "1h0m", "100.0%", "50.0%" },
+        { "Reading", "",      "",      "",     "",       "50.0%" },
+      },
+      [] string { "Total", "4h30m", "", "1h0m", "", "" },
+    },
+    {
+      "bars", ActivityRecordChartOptions { Title: "Week" },
+      "Week",
+      [] string { "Day", "Coding", "Email", "Total" },
+      [][] string {
+        { "2026-03-02", "3h0m", "1h0m",  "4h0m" },
+        { "2026-03-03", "0h0m", "0h0m",  "0h0m" },
+        { "2026-03-04", "0h0m", "0h30m", "0h30m" },
+      },
+      [] string { "Total", "3h0m", "1h30m", "4h30m" },
+    },
+    {
+      "lines", ActivityRecordChartOptions { Group_by: "activity", Rolling_days: [] int { 2 } },
+      "Time per day by activity, 2-day rolling average",
+      [] string { "Day", "Coding", "Coding, 2-day average", "Email", "Email, 2-day average" },
+      [][] string { { "2026-03-03", "0h0m", "1h30m", "0h0m", "0h30m" } },
+      nil,
+    },
+    {
+      "timeline", ActivityRecordChartOptions { Group_by: "day" },
+      "Timeline by day",
+      [] string { "Day", "Activity", "Start", "End", "Duration", "Comment" },
+      [][] string { { "2026-03-04", "Email", "2026-03-04 09:00", "2026-03-04 09:30", "0h30m", "" } },
+      nil,
+    },
+    {
+      "clock", ActivityRecordChartOptions { Average: true },
+      "Typical day by activity, over 3 days",
+      [] string { "Time", "Coding", "Email" },
+      [][] string { { "09:00–09:15", "0.0m", "10.0m" }, { "12:45–13:00", "5.0m", "0.0m" } },
+      nil,
+    },
+    {
+      "calendar", ActivityRecordChartOptions {},
+      "Time per day",
+      [] string { "Day", "Time" },
+      [][] string { { "2026-03-02", "4h0m" }, { "2026-03-04", "0h30m" } },
+      [] string { "Total", "4h30m" },
+    },
+    {
+      "heatmap", ActivityRecordChartOptions {},
+      "Time by weekday and hour",
+      nil,
+      [][] string { {
+        "Monday",
+        "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "1h0m", "1h0m", "1h0m",
+        "1h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m", "0h0m",
+        "4h0m",
+      } },
+      nil,
+    },
+    {
+      "sunburst", ActivityRecordChartOptions {},
+      "Time by category and activity",
+      [] string { "Category", "Activity", "Time", "Share" },
+      [][] string { { "Work", "", "4h30m", "100.0%" } },
+      [] string { "Total", "", "4h30m", "" },
+    },
+    {
+      "transitions", ActivityRecordChartOptions {},
+      "Transitions between activities",
+      [] string { "From", "To", "Transitions", "Share" },
+      [][] string { { "Email", "Coding", "1", "100.0%" } },
+      [] string { "Total", "", "1", "" },
+    },
+  }
+
+  for _, test := range tests {
+    chart, err := LookupChart(test.chart)
+    if err != nil { t.Fatal(err) }
+    table := chart.Table(records, &test.options)
+
+    if table.Caption != test.caption {
+      t.Errorf("%s: caption %q, want %q", test.chart, table.Caption, test.caption)
+    }
+    if test.columns != nil && strings.Join(table.Columns, "|") != strings.Join(test.columns, "|") {
+      t.Errorf("%s: columns %q, want %q", test.chart, table.Columns, test.columns)
+    }
+    for _, want := range test.rows {
+      got := testChartTableRow(table, want[0])
+      if strings.Join(got, "|") != strings.Join(want, "|") {
+        t.Errorf("%s: row %q, want %q", test.chart, got, want)
+      }
+    }
+    if strings.Join(table.Footer, "|") != strings.Join(test.footer, "|") {
+      t.Errorf("%s: footer %q, want %q", test.chart, table.Footer, test.footer)
+    }
+  }
+}
+
+
+func TestChartsTablesShape (t * testing.T) {
+  // Every chart has a table, with as many cells in each row as it has
+  // columns, that's well-formed HTML, with no records or a few
+  sets := map [string] [] ActivityRecord {
+    "no records":  nil,
+    "one record":  { testRecord("Email", "2026-03-02 09:00", "2026-03-02 10:00") },
+    "no duration": { testRecord("Email", "2026-03-02 09:00", "2026-03-02 09:00") },
+    "several": {
+      testRecord("Email",  "2026-03-02 09:00", "2026-03-02 10:00"),
+      testRecord("Coding", "2026-03-02 10:00", "2026-03-02 13:00"),
+      testRecord("Email",  "2026-03-04 09:00", "2026-03-04 09:30"),
+    },
+  }
+
+  for _, name := range ChartNames() {
+    chart, err := LookupChart(name)
+    if err != nil { t.Fatal(err) }
+
+    for set_name, records := range sets {
+      for _, options := range [] * ActivityRecordChartOptions { nil, { Group_by: "category" } } {
+        table := chart.Table(records, options)
+        if table.Caption == "" {
+          t.Errorf("%s, %s: no caption", name, set_name)
+        }
+        for _, row := range append(table.Rows, table.Footer) {
+          if row != nil && len(row) != len(table.Columns) {
+            t.Errorf("%s, %s: row %q for columns %q", name, set_name, row, table.Columns)
+          }
+        }
+
+        var html bytes.Buffer
+        if err := table.WriteHTML(&html); err != nil { t.Fatal(err) }
+        decoder := xml.NewDecoder(&html)
+        for {
+          _, err := decoder.Token()
+          if err == io.EOF { break }
+          if err != nil {
+            t.Fatalf("%s, %s: %v", name, set_name, err)
+          }
+        }
+      }
+    }
+  }
+}
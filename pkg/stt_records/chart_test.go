@@ -0,0 +1,109 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "errors"
+  "strings"
+  "testing"
+)
+
+
+// A writer that fails once it's been given more than its limit
+type testLimitWriter struct {
+  limit   int;
+  written int;
+}
+
+var errTestLimit error = errors.New("limit reached")
+
+func (writer * testLimitWriter) Write (data [] byte) (int, error) {
+  if writer.written + len(data) > writer.limit {
+    return 0, errTestLimit
+  }
+  writer.written += len(data)
+  return len(data), nil
+}
+
+
+func TestChartsRenderToWriters (t * testing.T) {
+  records := [] ActivityRecord {
+    testRecord("Email",  "2026-03-02 09:00", "2026-03-02 10:00"),
+    testRecord("Coding", "2026-03-02 10:00", "2026-03-02 12:00"),
+    testRecord("Email",  "2026-03-03 09:00", "2026-03-03 09:30"),
+  }
+
+  for _, name := range ChartNames() {
+    var svg bytes.Buffer
+    if err := RenderChart(&svg, name, records, nil); err != nil {
+      t.Fatalf("%s: %v", name, err)
+    }
+    if ! strings.HasPrefix(svg.String(), "<svg ") || ! strings.HasSuffix(svg.String(), "</svg>") {
+      t.Errorf("%s: not a whole document:\n%s", name, svg.String())
+    }
+
+    // Writing stops at the writer's first error, which comes back
+    writer := & testLimitWriter { limit: svg.Len() / 2 }
+    if err := RenderChart(writer, name, records, nil); ! errors.Is(err, errTestLimit) {
+      t.Errorf("%s: got %v from a failing writer, want its error", name, err)
+    }
+  }
+
+  if err := RenderChart(&bytes.Buffer {}, "gantt", records, nil); err == nil {
+    t.Errorf("no error for an unknown chart")
+  }
+}
+
+
+func TestChartsFewRecords (t * testing.T) {
+  // Every chart draws with no records, one, or one with no time, with each
+  // of its options, and says when there's nothing to show
+  one := testRecord("Email", "2026-03-02 09:00", "2026-03-02 10:00")
+  one.Categories  = [] string { "Work" }
+  one.Record_tags = "inbox"
+  instant := testRecord("Email", "2026-03-02 09:00", "2026-03-02 09:00")
+  instant.Categories = [] string { "Work" }
+
+  tests := [] struct {
+    name    string;
+    records [] ActivityRecord;
+    empty   bool;
+  } {
+    { "no records",     nil,                          true },
+    { "empty records",  [] ActivityRecord {},         true },
+    { "one record",     [] ActivityRecord { one },     false },
+    { "no time",        [] ActivityRecord { instant }, true },
+  }
+
+  variants := [] ActivityRecordChartOptions {
+    {},
+    { Group_by: "category" },
+    { Group_by: "tag" },
+    { Average: true },
+    { Donut: true, Legend: true, Order: "name", Other_percent: 50 },
+    { Rolling_days: [] int { 7, 28 } },
+    { Compare_records: [] ActivityRecord { one }, Targets: map [string] float64 { "Email": 1 } },
+    { Theme: "dark", Width: "100%" },
+  }
+
+  for _, name := range ChartNames() {
+    chart_variants := variants
+    if name == "timeline" {
+      chart_variants = append(chart_variants, ActivityRecordChartOptions { Group_by: "day" })
+    }
+
+    for _, test := range tests {
+      for variant_i := range chart_variants {
+        svg := testChartSvg(t, name, test.records, &chart_variants[variant_i])
+
+        _, description, _ := strings.Cut(svg, "<desc>")
+        description, _, _  = strings.Cut(description, "</desc>")
+        // Transitions need two records
+        nothing := test.empty || name == "transitions"
+        if strings.Contains(description, "No ") != nothing {
+          t.Errorf("%s, %s, %+v: description %q", name, test.name, chart_variants[variant_i], description)
+        }
+      }
+    }
+  }
+}
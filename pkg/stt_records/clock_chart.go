@@ -0,0 +1,256 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "time"
+)
+
+
+const CLOCK_CHART_SLOT_MINUTES int = 15
+
+
+func clockAngle (minute_of_day float64) float64 {
+  /*
+    The angle on a 24-hour dial of a minute of the day, with midnight at the
+    top and the hours running clockwise.
+  */
+  return 2 * math.Pi * minute_of_day / (24 * 60) - math.Pi / 2
+}
+
+
+func clockMinuteOfDay (datetime time.Time) float64 {
+  return float64(datetime.Hour() * 60 + datetime.Minute()) + float64(datetime.Second()) / 60
+}
+
+
+func activityRecordsClockSlots (records [] ActivityRecord, series_key string, series_groups [] AggregateGroup) (slot_minutes [][] float64, days float64) {
+  /*
+    The minutes each series takes in each quarter hour, as
+    slot_minutes[slot_i][series_i], spread across the slots each record
+    covers, then averaged over the days from the first record's to the final
+    record's.
+  */
+
+  series_indices := make(map [string] int, len(series_groups))
+  for series_i, series := range series_groups {
+    series_indices[series.Keys[0]] = series_i
+  }
+
+  slots := 24 * 60 / CLOCK_CHART_SLOT_MINUTES
+  slot_minutes = make([][] float64, slots)
+  for slot_i := range slot_minutes {
+    slot_minutes[slot_i] = make([] float64, len(series_groups))
+  }
+
+  day_groups, _ := ActivityRecordsAggregate(records, "day")
+  days = 1.0
+  if len(day_groups) > 0 {
+    first_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_groups[0].Keys[0], SttLocation())
+    final_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_groups[len(day_groups)-1].Keys[0], SttLocation())
+    days = math.Round(final_day.Sub(first_day).Hours() / 24) + 1
+  }
+
+  for record_i := range records {
+    record := &records[record_i]
+    start  := record.Time_started
+    for start.Before(record.Time_ended) {
+      // Slots end on the quarter hours of the clock, in the records' time
+      // zone, whatever its offset
+      minute   := int(clockMinuteOfDay(start))
+      year, month, day := start.Date()
+      slot_end := time.Date(
+        year, month, day, start.Hour(), (start.Minute() / CLOCK_CHART_SLOT_MINUTES + 1) * CLOCK_CHART_SLOT_MINUTES, 0, 0,
+        start.Location(),
+      )
+      if slot_end.After(record.Time_ended) {
+        slot_end = record.Time_ended
+      }
+      for _, value := range aggregateKeyValues(record, series_key) {
+        slot_minutes[minute / CLOCK_CHART_SLOT_MINUTES][series_indices[value.value]] += slot_end.Sub(start).Minutes() / days
+      }
+      start = slot_end
+    }
+  }
+  return slot_minutes, days
+}
+
+
+func activityRecordsClockSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot records on a 24-hour dial, each as an arc from its start to its end
+    time, colored by activity (or category or tag, per the Group_by option).
+    With the Average option, plot a typical day of the records' days instead:
+    the minutes each series takes in each quarter hour, averaged over the days
+    from the first record's to the final record's, as stacked bars out from
+    the dial.
+  */
+
+  options = svgChartOptions(options, "400", "260")
+  series_key := chartSeriesKey(options)
+
+  series_groups, _ := ActivityRecordsAggregate(records, series_key)
+  AggregateGroupsSortBySum(series_groups)
+
+  // Geometry, in viewBox units: the dial around the origin, and the legend
+  // to its right
+
+  const dial_r       float64 = 60
+  const ring_width   float64 = 30
+  const label_r      float64 = 112
+  const legend_left  float64 = 135
+  const legend_row   float64 = 16
+
+  title := "Time of day by " + series_key
+  if options.Average {
+    title = "Typical day by " + series_key
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { -130, -130, 420, 260 },
+    title, chartDescribeRecords(records, series_groups),
+    "    path.arc:hover { filter: brightness(1.1); stroke: #555; stroke-width: 0.5; }\n" +
+    "    circle.dial { fill: none; stroke: #8886; stroke-width: 0.5; }\n" +
+    "    line.tick { stroke: #888; stroke-width: 0.5; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n" +
+    "    text.total { font-size: 12px; fill: #444; }\n",
+  )
+
+  svg.Add("circle").Attr("class", "dial").Num("r", dial_r)
+  svg.Add("circle").Attr("class", "dial").Num("r", dial_r + ring_width)
+
+  var total_minutes uint = 0
+  for _, series := range series_groups {
+    total_minutes += series.Minutes
+  }
+
+  if options.Average {
+
+    slot_minutes, days := activityRecordsClockSlots(records, series_key, series_groups)
+    slot_duration := time.Duration(CLOCK_CHART_SLOT_MINUTES) * time.Minute
+
+    for slot_i := range slot_minutes {
+      start_t := clockAngle(float64(slot_i * CLOCK_CHART_SLOT_MINUTES))
+      end_t   := clockAngle(float64((slot_i + 1) * CLOCK_CHART_SLOT_MINUTES))
+      slot_start := time.Time {}.Add(time.Duration(slot_i) * slot_duration)
+      stacked    := 0.0
+
+      for series_i, minutes := range slot_minutes[slot_i] {
+        if minutes == 0 { continue }
+
+        inner_r := dial_r + ring_width * stacked / float64(CLOCK_CHART_SLOT_MINUTES)
+        stacked += minutes
+        outer_r := dial_r + ring_width * stacked / float64(CLOCK_CHART_SLOT_MINUTES)
+        svg.Add("path").Attr("class", "arc").
+          Path(svgArcPath(start_t, end_t, inner_r, outer_r)).
+          Attr("fill", chartColor(options, series_groups[series_i].Keys[0])).
+          Title(fmt.Sprintf(
+            "%s, %s–%s: %.1fm a day",
+            series_groups[series_i].Keys[0], slot_start.Format("15:04"), slot_start.Add(slot_duration).Format("15:04"), minutes,
+          ))
+      }
+    }
+
+    svg.Add("text").Attr("class", "total").Attr("text-anchor", "middle").
+      Text(minutesFormatDuration(uint(math.Round(float64(total_minutes) / days))))
+    svg.Add("text").Num("y", 12).Attr("text-anchor", "middle").
+      Text(fmt.Sprintf("a day, over %g days", days))
+
+  } else {
+    for record_i := range records {
+      record  := &records[record_i]
+      start_t := clockAngle(clockMinuteOfDay(record.Time_started))
+      end_t   := start_t + 2 * math.Pi * record.Time_ended.Sub(record.Time_started).Hours() / 24
+      if ! (end_t > start_t) { continue }
+
+      for _, value := range aggregateKeyValues(record, series_key) {
+        svg.Add("path").Attr("class", "arc").
+          Path(svgArcPath(start_t, end_t, dial_r, dial_r + ring_width)).
+          Attr("fill", chartColor(options, value.value)).
+          Title(timelineBarTitle(record))
+      }
+    }
+
+    svg.Add("text").Attr("class", "total").Attr("text-anchor", "middle").Attr("dominant-baseline", "middle").
+      Text(minutesFormatDuration(total_minutes))
+  }
+
+  // Hour ticks, and labels every third hour
+
+  for hour := 0; hour < 24; hour++ {
+    t := clockAngle(float64(hour * 60))
+    tick_r := dial_r - 3
+    if hour % 3 == 0 {
+      tick_r = dial_r - 6
+      svg.Add("text").
+        Num("x", label_r * math.Cos(t)).Num("y", label_r * math.Sin(t)).
+        Attr("text-anchor", "middle").Attr("dominant-baseline", "middle").
+        Text(fmt.Sprintf("%02d", hour))
+    }
+    svg.Add("line").Attr("class", "tick").
+      Num("x1", tick_r * math.Cos(t)).Num("y1", tick_r * math.Sin(t)).
+      Num("x2", dial_r * math.Cos(t)).Num("y2", dial_r * math.Sin(t))
+  }
+
+  // Legend
+
+  for series_i, series := range series_groups {
+    row_y := -120 + float64(series_i) * legend_row
+    svg.Add("rect").
+      Num("x", legend_left).Num("y", row_y).Num("width", 10).Num("height", 10).
+      Attr("fill", chartColor(options, series.Keys[0]))
+    svg.Add("text").
+      Num("x", legend_left + 14).Num("y", row_y + 5).Attr("dominant-baseline", "middle").
+      Text(fmt.Sprintf("%s (%s)", series.Keys[0], minutesFormatDuration(series.Minutes)))
+  }
+
+  return svg
+}
+
+
+func activityRecordsClockTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per arc: per record and series, by start time. With the Average
+    option, a row per quarter hour that has any time, with each series'
+    minutes in it a day.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  series_key := chartSeriesKey(options)
+  series_groups, _ := ActivityRecordsAggregate(records, series_key)
+  AggregateGroupsSortBySum(series_groups)
+
+  if ! options.Average {
+    table := activityRecordsTimelineTable(records, & ActivityRecordChartOptions { Group_by: series_key })
+    table.Caption = chartTitle(options, "Time of day by " + series_key)
+    return table
+  }
+
+  slot_minutes, days := activityRecordsClockSlots(records, series_key, series_groups)
+  slot_duration := time.Duration(CLOCK_CHART_SLOT_MINUTES) * time.Minute
+
+  table := & ChartTable {
+    Caption: chartTitle(options, fmt.Sprintf("Typical day by %s, over %g days", series_key, days)),
+    Columns: [] string { "Time" },
+  }
+  for _, series := range series_groups {
+    table.Columns = append(table.Columns, series.Keys[0])
+  }
+  for slot_i := range slot_minutes {
+    slot_start := time.Time {}.Add(time.Duration(slot_i) * slot_duration)
+    row := [] string { slot_start.Format("15:04") + "–" + slot_start.Add(slot_duration).Format("15:04") }
+    slot_total := 0.0
+    for _, minutes := range slot_minutes[slot_i] {
+      row = append(row, fmt.Sprintf("%.1fm", minutes))
+      slot_total += minutes
+    }
+    if slot_total > 0 {
+      table.Rows = append(table.Rows, row)
+    }
+  }
+  return table
+}
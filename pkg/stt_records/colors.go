@@ -0,0 +1,319 @@
+package stt_records;
+
+
+import (
+  "encoding/csv"
+  "fmt"
+  "hash/fnv"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+
+//
+// Chart colors: each activity, category or tag gets the same color on every
+// chart and every page load, picked from COLOR_PALETTE by a hash of its name,
+// unless STT_COLORS gives it one. STT's CSV export doesn't include the app's
+// activity colors, so to match them, copy them into STT_COLORS.
+//
+// With only so many colors, names' hashes often pick the same one, so
+// ColorAssignRecords settles the colors of the names in a set of records up
+// front, sharing the palette out between them. Those assignments are saved
+// (see ColorAssignmentsSave), so that names keep their colors when new ones
+// turn up, or old ones age out of the records.
+//
+
+
+// Paul Tol's "muted" qualitative scheme, distinguishable with the common
+// kinds of color blindness
+var COLOR_PALETTE [] string = [] string {
+  "#88ccee",
+  "#cc6677",
+  "#ddcc77",
+  "#117733",
+  "#332288",
+  "#aa4499",
+  "#44aa99",
+  "#999933",
+  "#882255",
+  "#661100",
+}
+
+
+var STT_COLORS map [string] string = map [string] string {}
+
+var color_assignments map [string] string = map [string] string {}
+
+
+func ParseColor (color_str string) (red, green, blue uint8, err error) {
+  /*
+    Parse a "#rrggbb" or "#rgb" hex color.
+  */
+
+  hex := strings.TrimPrefix(strings.TrimSpace(color_str), "#")
+  if len(hex) == 3 {
+    hex = string([] byte { hex[0], hex[0], hex[1], hex[1], hex[2], hex[2] })
+  }
+  if len(hex) != 6 {
+    return 0, 0, 0, fmt.Errorf("invalid color \"%s\", expected #rrggbb", color_str)
+  }
+
+  value, err := strconv.ParseUint(hex, 16, 32)
+  if err != nil {
+    return 0, 0, 0, fmt.Errorf("invalid color \"%s\", expected #rrggbb", color_str)
+  }
+  return uint8(value >> 16), uint8(value >> 8), uint8(value), nil
+}
+
+
+func formatColor (red, green, blue uint8) string {
+  return fmt.Sprintf("#%02x%02x%02x", red, green, blue)
+}
+
+
+func ParseColorOverrides (overrides_str string) (map [string] string, error) {
+  /*
+    Parse comma-separated "Name=#rrggbb" pairs, as in STT_COLORS.
+  */
+
+  overrides := make(map [string] string)
+  for _, pair := range strings.Split(overrides_str, ",") {
+    if strings.TrimSpace(pair) == "" { continue }
+
+    name, color_str, found := strings.Cut(pair, "=")
+    name = strings.TrimSpace(name)
+    if ! found || name == "" {
+      return nil, fmt.Errorf("invalid color override \"%s\", expected Name=#rrggbb", pair)
+    }
+
+    red, green, blue, err := ParseColor(color_str)
+    if err != nil { return nil, err }
+    overrides[name] = formatColor(red, green, blue)
+  }
+  return overrides, nil
+}
+
+
+func colorHashIndex (name string) int {
+  hash := fnv.New32a()
+  hash.Write([] byte(name))
+  return int(hash.Sum32() % uint32(len(COLOR_PALETTE)))
+}
+
+
+func SttGetColorsPath () string {
+  colors_path, ok := os.LookupEnv("STT_COLORS_PATH")
+  if ! ok {
+    return "stt_colors.csv"
+  }
+  return colors_path
+}
+
+
+func ColorAssignmentsLoad (colors_path string) error {
+  /*
+    Load the colors names were assigned before, from a CSV file of names and
+    colors, as ColorAssignmentsSave writes. A missing file has none, and is
+    not an error. Colors that aren't in COLOR_PALETTE (any more) are
+    dropped, for ColorAssignRecords to assign again.
+  */
+
+  colors_file, err := os.Open(colors_path)
+  if err != nil {
+    if os.IsNotExist(err) { return nil }
+    return err
+  }
+  defer colors_file.Close()
+
+  palette := make(map [string] bool, len(COLOR_PALETTE))
+  for _, color := range COLOR_PALETTE {
+    palette[color] = true
+  }
+
+  csv_reader := csv.NewReader(colors_file)
+  csv_reader.FieldsPerRecord = 2
+
+  assignments := make(map [string] string)
+  for row_i := 0; ; row_i++ {
+    row, err := csv_reader.Read()
+    if err == io.EOF { break }
+    if err != nil { return err }
+    if row_i == 0 && row[0] == "name" { continue }
+
+    if palette[row[1]] {
+      assignments[row[0]] = row[1]
+    }
+  }
+  color_assignments = assignments
+  return nil
+}
+
+
+func ColorAssignmentsSave (colors_path string) error {
+  /*
+    Save the names' assigned colors, by name, to a CSV file.
+  */
+
+  names := make([] string, 0, len(color_assignments))
+  for name := range color_assignments {
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  // Write a new file and move it into place, so that a failed write can't
+  // lose the old one
+  temp_path := colors_path + ".tmp"
+  colors_file, err := os.Create(temp_path)
+  if err != nil { return err }
+
+  csv_writer := csv.NewWriter(colors_file)
+  csv_writer.Write([] string { "name", "color" })
+  for _, name := range names {
+    csv_writer.Write([] string { name, color_assignments[name] })
+  }
+  csv_writer.Flush()
+  err = csv_writer.Error()
+  if close_err := colors_file.Close(); err == nil {
+    err = close_err
+  }
+  if err != nil {
+    os.Remove(temp_path)
+    return err
+  }
+  return os.Rename(temp_path, colors_path)
+}
+
+
+func ColorAssignRecords (records [] ActivityRecord) (assigned int) {
+  /*
+    Assign palette colors to the records' activities, categories and tags
+    that don't have one yet, in name order, each the color its hash picks
+    unless fewer names have another one, looking on through the palette from
+    there. Names keep the colors they were assigned before, and names with
+    STT_COLORS overrides keep those. Returns how many names it assigned
+    colors to, which need saving.
+  */
+
+  name_set := make(map [string] bool)
+  for record_i := range records {
+    record := &records[record_i]
+    name_set[record.Activity_name] = true
+    for _, category := range record.Categories {
+      if category != "" {
+        name_set[category] = true
+      }
+    }
+    for _, tag := range record.Tags() {
+      name_set[tag] = true
+    }
+  }
+
+  names := make([] string, 0, len(name_set))
+  for name := range name_set {
+    if _, found := STT_COLORS[name]; found { continue }
+    if _, found := color_assignments[name]; found { continue }
+    names = append(names, name)
+  }
+  sort.Strings(names)
+
+  uses := make([] int, len(COLOR_PALETTE))
+  countUse := func (color string) {
+    for palette_i, palette_color := range COLOR_PALETTE {
+      if color == palette_color {
+        uses[palette_i]++
+      }
+    }
+  }
+  for _, color := range STT_COLORS {
+    countUse(color)
+  }
+  for name, color := range color_assignments {
+    if _, found := STT_COLORS[name]; ! found {
+      countUse(color)
+    }
+  }
+
+  for _, name := range names {
+    hash_i := colorHashIndex(name)
+    best_i := hash_i
+    for offset := 1; offset < len(COLOR_PALETTE); offset++ {
+      palette_i := (hash_i + offset) % len(COLOR_PALETTE)
+      if uses[palette_i] < uses[best_i] {
+        best_i = palette_i
+      }
+    }
+    uses[best_i]++
+    color_assignments[name] = COLOR_PALETTE[best_i]
+  }
+  return len(names)
+}
+
+
+func ColorForName (name string) string {
+  /*
+    The color of an activity, category or tag: its STT_COLORS override, its
+    color from ColorAssignRecords, or one from COLOR_PALETTE picked by a hash
+    of its name.
+  */
+
+  if color, found := STT_COLORS[name]; found {
+    return color
+  }
+  if color, found := color_assignments[name]; found {
+    return color
+  }
+  return COLOR_PALETTE[colorHashIndex(name)]
+}
+
+
+func ColorVariant (color string, theme string) string {
+  /*
+    A color adjusted for a theme's background: unchanged for "light" (or no
+    theme), and mixed a third of the way to white for "dark", so that the
+    palette's deeper colors still stand out.
+  */
+
+  if theme != "dark" { return color }
+
+  red, green, blue, err := ParseColor(color)
+  if err != nil { return color }
+
+  lighten := func (channel uint8) uint8 {
+    return channel + uint8((255 - uint(channel)) / 3)
+  }
+  return formatColor(lighten(red), lighten(green), lighten(blue))
+}
+
+
+func ColorContrastText (color string) string {
+  /*
+    A text color that reads on top of the given one: dark on light colors,
+    and light on dark ones, by their relative luminance.
+  */
+
+  red, green, blue, err := ParseColor(color)
+  if err != nil { return "#333" }
+
+  luminance := (0.2126 * float64(red) + 0.7152 * float64(green) + 0.0722 * float64(blue)) / 255
+  if luminance < 0.5 {
+    return "#fff"
+  }
+  return "#333"
+}
+
+
+func ValidateTheme (theme string) error {
+  switch theme {
+  case "", "light", "dark":
+    return nil
+  }
+  return fmt.Errorf("unknown theme \"%s\", expected light or dark", theme)
+}
+
+
+func chartColor (options * ActivityRecordChartOptions, name string) string {
+  return ColorVariant(ColorForName(name), options.Theme)
+}
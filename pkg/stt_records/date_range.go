@@ -0,0 +1,208 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "strings"
+  "time"
+)
+
+
+//
+// Date range expressions, resolved to whole days. Days are the same days as
+// DayStart's: they begin STT_DAY_OFFSET after midnight, in SttLocation().
+//
+//   today, yesterday
+//   this week, last week, this month, last month, this year, last year
+//   last N days, last N weeks, last N months   (rolling, ending today)
+//   2024, 2024-03, 2024-03-15, 2024-Q3
+//   2024-W07                                   (the week, per STT_WEEK_START,
+//                                               sharing ISO week 7's Thursday)
+//   A..B, A.., ..B                             (from A's first day through
+//                                               B's last day; open ends are
+//                                               unbounded)
+//
+
+
+type DateRange struct {
+  /*
+    From Start, inclusive, to End, exclusive. A zero Start or End is
+    unbounded.
+  */
+  Start time.Time;
+  End   time.Time;
+}
+
+
+var dateRangeLastRgx    = regexp.MustCompile(`^(?:last|past) (\d+) (day|week|month)s?$`)
+var dateRangeQuarterRgx = regexp.MustCompile(`^(\d{4})-?q([1-4])$`)
+var dateRangeWeekRgx    = regexp.MustCompile(`^(\d{4})-?w(\d{1,2})$`)
+
+
+func newDayRange (first_day, last_day time.Time) DateRange {
+  return DateRange {
+    Start: first_day.Add(STT_DAY_OFFSET),
+    End:   last_day.AddDate(0, 0, 1).Add(STT_DAY_OFFSET),
+  }
+}
+
+
+func dayDate (year int, month time.Month, day int) time.Time {
+  return time.Date(year, month, day, 0, 0, 0, 0, SttLocation())
+}
+
+
+func parseDateRangeTerm (term string, now time.Time) (date_range DateRange, err error) {
+  today    := DayStart(now.In(SttLocation()))
+  y, m, _  := today.Date()
+
+  switch term {
+  case "today":
+    return newDayRange(today, today), nil
+  case "yesterday":
+    yesterday := today.AddDate(0, 0, -1)
+    return newDayRange(yesterday, yesterday), nil
+  case "this week":
+    week_start := WeekStart(today)
+    return newDayRange(week_start, week_start.AddDate(0, 0, 6)), nil
+  case "last week":
+    week_start := WeekStart(today).AddDate(0, 0, -7)
+    return newDayRange(week_start, week_start.AddDate(0, 0, 6)), nil
+  case "this month":
+    return newDayRange(dayDate(y, m, 1), dayDate(y, m + 1, 0)), nil
+  case "last month":
+    return newDayRange(dayDate(y, m - 1, 1), dayDate(y, m, 0)), nil
+  case "this year":
+    return newDayRange(dayDate(y, 1, 1), dayDate(y, 12, 31)), nil
+  case "last year":
+    return newDayRange(dayDate(y - 1, 1, 1), dayDate(y - 1, 12, 31)), nil
+  }
+
+  if match := dateRangeLastRgx.FindStringSubmatch(term); match != nil {
+    count, _ := strconv.Atoi(match[1])
+    if count < 1 {
+      return date_range, fmt.Errorf("invalid date range \"%s\"", term)
+    }
+
+    var first_day time.Time
+    switch match[2] {
+    case "day":   first_day = today.AddDate(0, 0, -(count - 1))
+    case "week":  first_day = today.AddDate(0, 0, -(7 * count - 1))
+    case "month":
+      // The day after the same day count months ago, or after the end of
+      // that month if it's shorter (AddDate would run over into the next)
+      year, month, day := today.Date()
+      month_days := dayDate(year, month - time.Month(count) + 1, 0).Day()
+      first_day = dayDate(year, month - time.Month(count), min(day, month_days)).AddDate(0, 0, 1)
+    }
+    return newDayRange(first_day, today), nil
+  }
+
+  if match := dateRangeQuarterRgx.FindStringSubmatch(term); match != nil {
+    year, _    := strconv.Atoi(match[1])
+    quarter, _ := strconv.Atoi(match[2])
+    first_month := time.Month(3 * (quarter - 1) + 1)
+    return newDayRange(dayDate(year, first_month, 1), dayDate(year, first_month + 3, 0)), nil
+  }
+
+  if match := dateRangeWeekRgx.FindStringSubmatch(term); match != nil {
+    year, _ := strconv.Atoi(match[1])
+    week, _ := strconv.Atoi(match[2])
+    week_start, err := WeekOfISO(year, week)
+    if err != nil { return date_range, err }
+    return newDayRange(week_start, week_start.AddDate(0, 0, 6)), nil
+  }
+
+  if date, err := time.ParseInLocation("2006-01-02", term, SttLocation()); err == nil {
+    return newDayRange(date, date), nil
+  }
+  if date, err := time.ParseInLocation("2006-01", term, SttLocation()); err == nil {
+    return newDayRange(date, date.AddDate(0, 1, -1)), nil
+  }
+  if date, err := time.ParseInLocation("2006", term, SttLocation()); err == nil {
+    return newDayRange(date, date.AddDate(1, 0, -1)), nil
+  }
+
+  return date_range, fmt.Errorf("invalid date range \"%s\"", term)
+}
+
+
+func ParseDateRange (expression string, now time.Time) (date_range DateRange, err error) {
+  /*
+    Resolve a date range expression, relative to now.
+  */
+
+  expression = strings.Join(strings.Fields(strings.ToLower(expression)), " ")
+  if expression == "" {
+    return date_range, fmt.Errorf("empty date range")
+  }
+
+  start_term, end_term, is_span := strings.Cut(expression, "..")
+  if ! is_span {
+    return parseDateRangeTerm(expression, now)
+  }
+
+  start_term = strings.TrimSpace(start_term)
+  end_term   = strings.TrimSpace(end_term)
+
+  if start_term != "" {
+    start_range, err := parseDateRangeTerm(start_term, now)
+    if err != nil { return date_range, err }
+    date_range.Start = start_range.Start
+  }
+
+  if end_term != "" {
+    end_range, err := parseDateRangeTerm(end_term, now)
+    if err != nil { return date_range, err }
+    date_range.End = end_range.End
+  }
+
+  if ! date_range.Start.IsZero() && ! date_range.End.IsZero() && ! date_range.Start.Before(date_range.End) {
+    return date_range, fmt.Errorf("date range \"%s\" ends before it starts", expression)
+  }
+
+  return date_range, nil
+}
+
+
+func (date_range DateRange) FirstDay () * time.Time {
+  /*
+    The first day in the range, as DayStart would return it; nil when the range
+    has no start. For ActivityRecordsFilterTimeRange's after_date.
+  */
+  if date_range.Start.IsZero() { return nil }
+  first_day := DayStart(date_range.Start)
+  return &first_day
+}
+
+
+func (date_range DateRange) LastDay () * time.Time {
+  /*
+    The last day in the range, as DayStart would return it; nil when the range
+    has no end. For ActivityRecordsFilterTimeRange's before_date.
+  */
+  if date_range.End.IsZero() { return nil }
+  last_day := DayStart(date_range.End).AddDate(0, 0, -1)
+  return &last_day
+}
+
+
+func (date_range DateRange) Contains (day time.Time) bool {
+  /*
+    Whether a day, as returned by DayStart, is within the range.
+  */
+  if first_day := date_range.FirstDay(); first_day != nil && day.Before(*first_day) { return false }
+  if last_day  := date_range.LastDay();  last_day  != nil && day.After(*last_day)   { return false }
+  return true
+}
+
+
+func (date_range DateRange) String () string {
+  start_str, end_str := "", ""
+  if first_day := date_range.FirstDay(); first_day != nil { start_str = first_day.Format(STT_DATE_LAYOUT) }
+  if last_day  := date_range.LastDay();  last_day  != nil { end_str   = last_day.Format(STT_DATE_LAYOUT) }
+  if start_str == end_str { return start_str }
+  return start_str + ".." + end_str
+}
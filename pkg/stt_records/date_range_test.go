@@ -0,0 +1,89 @@
+package stt_records;
+
+
+import (
+  "strings"
+  "testing"
+  "time"
+)
+
+
+func TestParseDateRange (t * testing.T) {
+  // A Tuesday, at the end of a month after a short one
+  now := time.Date(2026, 3, 31, 15, 0, 0, 0, time.UTC)
+
+  tests := [] struct {
+    expression string;
+    want       string;  // as DateRange.String writes it, or part of the error
+  } {
+    { "today",             "2026-03-31" },
+    { "yesterday",         "2026-03-30" },
+    { "this week",         "2026-03-30..2026-04-05" },
+    { "last week",         "2026-03-23..2026-03-29" },
+    { "this month",        "2026-03-01..2026-03-31" },
+    { "last month",        "2026-02-01..2026-02-28" },
+    { "this year",         "2026-01-01..2026-12-31" },
+    { "last year",         "2025-01-01..2025-12-31" },
+    { "last 7 days",       "2026-03-25..2026-03-31" },
+    { "Past 2  Weeks",     "2026-03-18..2026-03-31" },
+    { "last 1 month",      "2026-03-01..2026-03-31" },
+    { "last 3 months",     "2026-01-01..2026-03-31" },
+    { "2024",              "2024-01-01..2024-12-31" },
+    { "2024-02",           "2024-02-01..2024-02-29" },
+    { "2024-03-15",        "2024-03-15" },
+    { "2024-Q3",           "2024-07-01..2024-09-30" },
+    { "2024q4",            "2024-10-01..2024-12-31" },
+    { "2024-W07",          "2024-02-12..2024-02-18" },
+    { "2020-W53",          "2020-12-28..2021-01-03" },
+    { "2024-01..2024-03",  "2024-01-01..2024-03-31" },
+    { "2024-03..",         "2024-03-01.." },
+    { "..2024-03",         "..2024-03-31" },
+
+    { "",                  "empty date range" },
+    { "last 0 days",       "invalid date range" },
+    { "next week",         "invalid date range" },
+    { "2024-W54",          "week" },
+    { "2024-03..2024-01",  "ends before it starts" },
+  }
+
+  for _, test := range tests {
+    date_range, err := ParseDateRange(test.expression, now)
+    if err != nil {
+      if ! strings.Contains(err.Error(), test.want) {
+        t.Errorf("%q: error %q, want %q", test.expression, err, test.want)
+      }
+      continue
+    }
+    if got := date_range.String(); got != test.want {
+      t.Errorf("%q: got %s, want %s", test.expression, got, test.want)
+    }
+  }
+}
+
+
+func TestParseDateRangeMonthEnds (t * testing.T) {
+  // "last N months" runs from the day after the same day N months ago, or
+  // after the end of that month if it's shorter
+  tests := [] struct {
+    today time.Time;
+    count string;
+    want  string;
+  } {
+    { time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), "1",  "2026-02-16..2026-03-15" },
+    { time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC), "1",  "2026-03-01..2026-03-31" },
+    { time.Date(2026, 5, 31, 12, 0, 0, 0, time.UTC), "3",  "2026-03-01..2026-05-31" },
+    { time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC), "12", "2023-03-01..2024-02-29" },
+    { time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC), "1",  "2026-01-01..2026-01-31" },
+  }
+
+  for _, test := range tests {
+    date_range, err := ParseDateRange("last " + test.count + " months", test.today)
+    if err != nil {
+      t.Errorf("%s: %v", test.today.Format(time.DateOnly), err)
+      continue
+    }
+    if got := date_range.String(); got != test.want {
+      t.Errorf("last %s months on %s: got %s, want %s", test.count, test.today.Format(time.DateOnly), got, test.want)
+    }
+  }
+}
@@ -0,0 +1,307 @@
+package stt_records;
+
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+  "time"
+)
+
+
+var EXPORT_FORMATS [] string = [] string {
+  "json",
+  "ndjson",
+  "markdown",
+  "csv",
+}
+
+
+var EXPORT_CONTENT_TYPES map [string] string = map [string] string {
+  "json":     "application/json",
+  "ndjson":   "application/x-ndjson",
+  "markdown": "text/markdown; charset=utf-8",
+  "md":       "text/markdown; charset=utf-8",
+  "csv":      "text/csv; charset=utf-8",
+}
+
+
+type ActivityRecordExport struct {
+  /*
+    The JSON form of an ActivityRecord. Durations are in whole seconds and
+    minutes, rather than the nanoseconds a time.Duration would marshal to.
+  */
+  Activity_name    string   `json:"activity_name"`;
+  Time_started     string   `json:"time_started"`;
+  Time_ended       string   `json:"time_ended"`;
+  Comment          string   `json:"comment"`;
+  Categories       []string `json:"categories"`;
+  Record_tags      string   `json:"record_tags"`;
+  Duration_seconds int64    `json:"duration_seconds"`;
+  Duration_minutes uint     `json:"duration_minutes"`;
+}
+
+
+type ActivityTotal struct {
+  Activity_name string  `json:"activity_name"`;
+  Records       int     `json:"records"`;
+  Minutes       uint    `json:"minutes"`;
+  Ratio         float64 `json:"ratio"`;
+}
+
+
+func (record *ActivityRecord) Export () ActivityRecordExport {
+  categories := record.Categories
+  if categories == nil {
+    categories = [] string {}
+  }
+
+  return ActivityRecordExport {
+    Activity_name:    record.Activity_name,
+    Time_started:     record.Time_started.Format(time.RFC3339),
+    Time_ended:       record.Time_ended.Format(time.RFC3339),
+    Comment:          record.Comment,
+    Categories:       categories,
+    Record_tags:      record.Record_tags,
+    Duration_seconds: int64(record.Duration / time.Second),
+    Duration_minutes: record.Duration_minutes,
+  }
+}
+
+
+func ActivityRecordsTotals (records [] ActivityRecord) [] ActivityTotal {
+  /*
+    Sum the minutes and count the records of each activity, largest total
+    first.
+  */
+
+  groups, _ := ActivityRecordsAggregate(records, "activity")
+  AggregateGroupsSortBySum(groups)
+
+  var records_duration uint = 0
+  for _, group := range groups {
+    records_duration += group.Minutes
+  }
+
+  totals := make([] ActivityTotal, len(groups))
+  for group_i, group := range groups {
+    totals[group_i] = ActivityTotal {
+      Activity_name: group.Keys[0],
+      Records:       group.Count,
+      Minutes:       group.Minutes,
+    }
+    if records_duration > 0 {
+      totals[group_i].Ratio = float64(group.Minutes) / float64(records_duration)
+    }
+  }
+
+  return totals
+}
+
+
+func exportJson (io_writer io.Writer, records [] ActivityRecord, totals bool) error {
+  encoder := json.NewEncoder(io_writer)
+  encoder.SetIndent("", "  ")
+
+  if totals {
+    return encoder.Encode(ActivityRecordsTotals(records))
+  }
+
+  exported := make([] ActivityRecordExport, len(records))
+  for record_i := range records {
+    exported[record_i] = records[record_i].Export()
+  }
+  return encoder.Encode(exported)
+}
+
+
+func exportNdjson (io_writer io.Writer, records [] ActivityRecord, totals bool) error {
+  encoder := json.NewEncoder(io_writer)
+
+  if totals {
+    for _, total := range ActivityRecordsTotals(records) {
+      if err := encoder.Encode(total); err != nil { return err }
+    }
+    return nil
+  }
+
+  for record_i := range records {
+    if err := encoder.Encode(records[record_i].Export()); err != nil { return err }
+  }
+  return nil
+}
+
+
+func markdownEscape (text string) string {
+  text = strings.ReplaceAll(text, `\`, `\\`)
+  text = strings.ReplaceAll(text, "|", `\|`)
+  text = strings.ReplaceAll(text, "\n", " ")
+  return text
+}
+
+
+func exportMarkdown (io_writer io.Writer, records [] ActivityRecord) (err error) {
+  /*
+    A summary table of activity totals, with a total row at the end.
+  */
+
+  var records_duration uint = 0
+  first_date, final_date := time.Time {}, time.Time {}
+
+  for record_i, record := range records {
+    records_duration += record.Duration_minutes
+    date := record.DayStart()
+    if record_i == 0 || date.Before(first_date) { first_date = date }
+    if record_i == 0 || date.After(final_date)  { final_date = date }
+  }
+
+  if len(records) > 0 {
+    _, err = fmt.Fprintf(
+      io_writer, "**%s – %s**\n\n",
+      first_date.Format(STT_DATE_LAYOUT), final_date.Format(STT_DATE_LAYOUT),
+    )
+    if err != nil { return err }
+  }
+
+  _, err = fmt.Fprint(io_writer,
+    "| Activity | Records | Time | Share |\n" +
+    "|:---------|--------:|-----:|------:|\n",
+  )
+  if err != nil { return err }
+
+  for _, total := range ActivityRecordsTotals(records) {
+    _, err = fmt.Fprintf(
+      io_writer, "| %s | %d | %s | %2.1f%% |\n",
+      markdownEscape(total.Activity_name),
+      total.Records,
+      minutesFormatDuration(total.Minutes),
+      total.Ratio * 100,
+    )
+    if err != nil { return err }
+  }
+
+  _, err = fmt.Fprintf(
+    io_writer, "| **Total** | **%d** | **%s** | |\n",
+    len(records), minutesFormatDuration(records_duration),
+  )
+  return err
+}
+
+
+func exportMarkdownGroups (io_writer io.Writer, groups [] AggregateGroup, keys [] string) (err error) {
+  header, align := "|", "|"
+  for _, key := range keys {
+    header += " " + strings.ToUpper(key[:1]) + key[1:] + " |"
+    align  += ":---|"
+  }
+  header += " Records | Time | Mean | Median | Max |\n"
+  align  += "---:|---:|---:|---:|---:|\n"
+
+  if _, err = fmt.Fprint(io_writer, header, align); err != nil { return err }
+
+  for _, group := range groups {
+    row := "|"
+    for _, value := range group.Keys {
+      row += " " + markdownEscape(value) + " |"
+    }
+    _, err = fmt.Fprintf(
+      io_writer, "%s %d | %s | %s | %s | %s |\n",
+      row, group.Count,
+      minutesFormatDuration(group.Minutes),
+      minutesFormatDuration(uint(group.Mean.Minutes())),
+      minutesFormatDuration(uint(group.Median.Minutes())),
+      minutesFormatDuration(uint(group.Max.Minutes())),
+    )
+    if err != nil { return err }
+  }
+
+  return nil
+}
+
+
+func exportCsvGroups (io_writer io.Writer, groups [] AggregateGroup, keys [] string) error {
+  csv_writer := csv.NewWriter(io_writer)
+
+  header := append([] string (nil), keys...)
+  header  = append(header, "count", "minutes", "sum_seconds", "mean_seconds", "median_seconds", "max_seconds")
+  if err := csv_writer.Write(header); err != nil { return err }
+
+  for _, group := range groups {
+    row := append([] string (nil), group.Keys...)
+    row  = append(
+      row,
+      strconv.Itoa(group.Count),
+      strconv.FormatUint(uint64(group.Minutes), 10),
+      strconv.FormatFloat(group.Sum.Seconds(), 'f', -1, 64),
+      strconv.FormatFloat(group.Mean.Seconds(), 'f', -1, 64),
+      strconv.FormatFloat(group.Median.Seconds(), 'f', -1, 64),
+      strconv.FormatFloat(group.Max.Seconds(), 'f', -1, 64),
+    )
+    if err := csv_writer.Write(row); err != nil { return err }
+  }
+
+  csv_writer.Flush()
+  return csv_writer.Error()
+}
+
+
+func ActivityRecordsExportGroups (io_writer io.Writer, records [] ActivityRecord, format string, keys [] string) error {
+  /*
+    Write the aggregate groups of records, grouped by keys (see
+    ActivityRecordsAggregate), in one of EXPORT_FORMATS.
+  */
+
+  groups, err := ActivityRecordsAggregate(records, keys...)
+  if err != nil { return err }
+
+  switch format {
+  case "json":
+    encoder := json.NewEncoder(io_writer)
+    encoder.SetIndent("", "  ")
+    if groups == nil {
+      groups = [] AggregateGroup {}
+    }
+    return encoder.Encode(groups)
+
+  case "ndjson":
+    encoder := json.NewEncoder(io_writer)
+    for _, group := range groups {
+      if err := encoder.Encode(group); err != nil { return err }
+    }
+    return nil
+
+  case "markdown", "md":
+    return exportMarkdownGroups(io_writer, groups, keys)
+
+  case "csv":
+    return exportCsvGroups(io_writer, groups, keys)
+  }
+
+  return fmt.Errorf("unknown export format \"%s\", expected one of: %s", format, strings.Join(EXPORT_FORMATS, ", "))
+}
+
+
+func ActivityRecordsExport (io_writer io.Writer, records [] ActivityRecord, format string, totals bool) error {
+  /*
+    Write records in one of EXPORT_FORMATS. When totals is set, the JSON
+    formats write per-activity totals instead of the records; markdown is
+    always a summary table, and csv is always the records, as STT exports
+    them (see SttCsvWrite), for importing back into STT.
+  */
+
+  switch format {
+  case "json":
+    return exportJson(io_writer, records, totals)
+  case "ndjson":
+    return exportNdjson(io_writer, records, totals)
+  case "markdown", "md":
+    return exportMarkdown(io_writer, records)
+  case "csv":
+    return SttCsvWrite(io_writer, records)
+  }
+
+  return fmt.Errorf("unknown export format \"%s\", expected one of: %s", format, strings.Join(EXPORT_FORMATS, ", "))
+}
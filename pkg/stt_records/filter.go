@@ -0,0 +1,141 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "net/url"
+  "strings"
+  "time"
+)
+
+
+const STT_DATE_LAYOUT string = time.DateOnly
+
+
+type ActivityRecordFilter struct {
+  /*
+    The date range and categories of ActivityRecordsFilterTimeRange and
+    ActivityRecordsFilterCategories, and a RecordQuery, gathered so that they
+    can be read from a URL query string or the command line. Nil dates, empty
+    categories and a nil query don't filter.
+  */
+  After      * time.Time;
+  Before     * time.Time;
+  Categories [] string;
+  Query      * RecordQuery;
+}
+
+
+func SttLocation () * time.Location {
+  /*
+    The location record times are parsed in.
+  */
+  if STT_TIMEZONE == nil {
+    return time.UTC
+  }
+  return STT_TIMEZONE
+}
+
+
+func SttParseDate (date_str string) (date time.Time, err error) {
+  date, err = time.ParseInLocation(STT_DATE_LAYOUT, date_str, SttLocation())
+  if err != nil {
+    return date, fmt.Errorf("invalid date \"%s\", expected YYYY-MM-DD", date_str)
+  }
+  return date, nil
+}
+
+
+func splitCategories (categories_str string) (categories [] string) {
+  for _, category := range strings.Split(categories_str, ",") {
+    category = strings.TrimSpace(category)
+    if category != "" {
+      categories = append(categories, category)
+    }
+  }
+  return categories
+}
+
+
+func (filter *ActivityRecordFilter) SetAfter (date_str string) error {
+  if date_str == "" { filter.After = nil ; return nil }
+  date, err := SttParseDate(date_str)
+  if err != nil { return err }
+  filter.After = &date
+  return nil
+}
+
+
+func (filter *ActivityRecordFilter) SetBefore (date_str string) error {
+  if date_str == "" { filter.Before = nil ; return nil }
+  date, err := SttParseDate(date_str)
+  if err != nil { return err }
+  filter.Before = &date
+  return nil
+}
+
+
+func (filter *ActivityRecordFilter) SetRange (expression string) error {
+  /*
+    Set both dates from a date range expression (see ParseDateRange).
+  */
+  if expression == "" { return nil }
+  date_range, err := ParseDateRange(expression, time.Now())
+  if err != nil { return err }
+  filter.After  = date_range.FirstDay()
+  filter.Before = date_range.LastDay()
+  return nil
+}
+
+
+func (filter *ActivityRecordFilter) AddCategories (categories_str string) {
+  /*
+    Add comma-separated categories to the filter.
+  */
+  filter.Categories = append(filter.Categories, splitCategories(categories_str)...)
+}
+
+
+func (filter *ActivityRecordFilter) SetQuery (query_str string) (err error) {
+  if strings.TrimSpace(query_str) == "" { filter.Query = nil ; return nil }
+  filter.Query, err = ParseRecordQuery(query_str)
+  return err
+}
+
+
+func ActivityRecordFilterFromQuery (query url.Values) (filter ActivityRecordFilter, err error) {
+  /*
+    Read a filter from the "range" (see ParseDateRange), "after", "before",
+    "category" and "q" (a RecordQuery) query parameters. Dates are
+    YYYY-MM-DD, and "category" may be repeated or comma-separated.
+  */
+
+  if err = filter.SetRange(query.Get("range")); err != nil { return }
+  if query.Has("after") {
+    if err = filter.SetAfter(query.Get("after")); err != nil { return }
+  }
+  if query.Has("before") {
+    if err = filter.SetBefore(query.Get("before")); err != nil { return }
+  }
+  if err = filter.SetQuery(query.Get("q")); err != nil { return }
+
+  for _, categories_str := range query["category"] {
+    filter.AddCategories(categories_str)
+  }
+
+  return filter, nil
+}
+
+
+func (filter *ActivityRecordFilter) Apply (records [] ActivityRecord) [] ActivityRecord {
+  if filter.After != nil || filter.Before != nil {
+    records = ActivityRecordsFilterTimeRange(records, filter.After, filter.Before)
+  }
+  if len(filter.Categories) > 0 {
+    records = ActivityRecordsFilterCategories(records, filter.Categories...)
+  }
+  if filter.Query != nil {
+    records = filter.Query.Filter(records)
+  }
+  return records
+}
@@ -0,0 +1,198 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "time"
+)
+
+
+// The color of the busiest hour's cell, for the light theme
+const HEATMAP_CHART_FILL string = "#2a7ab0"
+
+
+func ActivityRecordsWeekdayHourMinutes (records [] ActivityRecord) (minutes [7][24] float64) {
+  /*
+    Minutes spent in each hour of each weekday, with each record spread across
+    the clock hours it covers, from Time_started to Time_ended. Weekdays are
+    indexed from STT_WEEK_START (see WeekdayIndex), and are those of the day,
+    per DayStart, each hour is in.
+  */
+
+  for _, record := range records {
+    start := record.Time_started
+    end   := record.Time_ended
+    if ! end.After(start) { continue }
+
+    for start.Before(end) {
+      // The end of the hour on the clock, which in time zones offset by
+      // half hours isn't where Truncate would put it
+      year, month, day := start.Date()
+      hour_end := time.Date(year, month, day, start.Hour() + 1, 0, 0, 0, start.Location())
+      if hour_end.After(end) {
+        hour_end = end
+      }
+
+      weekday_i := WeekdayIndex(DayStart(start).Weekday())
+      minutes[weekday_i][start.Hour()] += hour_end.Sub(start).Minutes()
+
+      start = hour_end
+    }
+  }
+
+  return minutes
+}
+
+
+func activityRecordsHeatmapSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot a 7×24 grid of minutes spent in each hour (columns) of each weekday
+    (rows), as cells shaded by their share of the busiest hour.
+  */
+
+  options = svgChartOptions(options, "600", "220")
+  minutes := ActivityRecordsWeekdayHourMinutes(records)
+  fill    := ColorVariant(HEATMAP_CHART_FILL, options.Theme)
+
+  var max_minutes float64 = 0
+  for weekday_i := range minutes {
+    for hour := range minutes[weekday_i] {
+      max_minutes = math.Max(max_minutes, minutes[weekday_i][hour])
+    }
+  }
+
+  // Geometry, in viewBox units: a label column and row, then the cells, then
+  // a row for the scale.
+
+  const cell_size     float64 = 20
+  const label_width   float64 = 40
+  const label_height  float64 = 20
+  const scale_height  float64 = 30
+
+  grid_width  := 24 * cell_size
+  grid_height := 7 * cell_size
+
+  // The description names the busiest hour
+
+  busiest_weekday_i, busiest_hour := 0, 0
+  for weekday_i := range minutes {
+    for hour := range minutes[weekday_i] {
+      if minutes[weekday_i][hour] > minutes[busiest_weekday_i][busiest_hour] {
+        busiest_weekday_i, busiest_hour = weekday_i, hour
+      }
+    }
+  }
+  description := "No records."
+  if max_minutes > 0 {
+    var total_minutes uint = 0
+    for record_i := range records {
+      total_minutes += records[record_i].Duration_minutes
+    }
+    description = fmt.Sprintf(
+      "%s %s. The busiest hour is %s %02d:00–%02d:00, with %s.",
+      minutesFormatDuration(total_minutes), chartDescribeSpan(records),
+      time.Weekday((busiest_weekday_i + int(STT_WEEK_START)) % 7), busiest_hour, busiest_hour + 1,
+      minutesFormatDuration(uint(math.Round(max_minutes))),
+    )
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, label_width + grid_width + 4, label_height + grid_height + scale_height },
+    "Time by weekday and hour", description,
+    "    rect.cell { stroke: #fff; stroke-width: 1; }\n" +
+    "    rect.cell:hover { stroke: #888; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n",
+  )
+
+  // Hour labels, every third hour
+
+  for hour := 0; hour < 24; hour += 3 {
+    svg.Add("text").
+      Num("x", label_width + (float64(hour) + 0.5) * cell_size).Num("y", label_height - 6).Attr("text-anchor", "middle").
+      Text(fmt.Sprintf("%02d", hour))
+  }
+
+  // Weekday labels and cells
+
+  for weekday_i := 0; weekday_i < 7; weekday_i++ {
+    weekday := time.Weekday((weekday_i + int(STT_WEEK_START)) % 7)
+    row_y   := label_height + float64(weekday_i) * cell_size
+
+    svg.Add("text").
+      Num("x", label_width - 6).Num("y", row_y + cell_size/2).Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+      Text(weekday.String()[:3])
+
+    for hour := 0; hour < 24; hour++ {
+      cell_minutes := minutes[weekday_i][hour]
+      opacity      := 0.0
+      if max_minutes > 0 {
+        opacity = cell_minutes / max_minutes
+      }
+
+      cell_x := label_width + float64(hour) * cell_size
+      svg.Add("rect").Attr("class", "cell").
+        Num("x", cell_x).Num("y", row_y).Num("width", cell_size).Num("height", cell_size).
+        Attr("fill", "#eee")
+      svg.Add("rect").Attr("class", "cell").
+        Num("x", cell_x).Num("y", row_y).Num("width", cell_size).Num("height", cell_size).
+        Attr("fill", fill).Num("fill-opacity", opacity).
+        Title(fmt.Sprintf(
+          "%s %02d:00–%02d:00: %s",
+          weekday.String(), hour, (hour + 1) % 24, minutesFormatDuration(uint(math.Round(cell_minutes))),
+        ))
+    }
+  }
+
+  // Scale, from no time to the busiest hour
+
+  scale_y  := label_height + grid_height + 10
+  gradient := svg.Add("defs").Add("linearGradient").Attr("id", "heatmap-scale")
+  gradient.Add("stop").Num("offset", 0).Attr("stop-color", fill).Num("stop-opacity", 0)
+  gradient.Add("stop").Num("offset", 1).Attr("stop-color", fill).Num("stop-opacity", 1)
+
+  svg.Add("rect").
+    Num("x", label_width).Num("y", scale_y).Num("width", 6 * cell_size).Num("height", 8).
+    Attr("fill", "url(#heatmap-scale)").Attr("stroke", "#ccc").Num("stroke-width", 0.5)
+  svg.Add("text").
+    Num("x", label_width - 4).Num("y", scale_y + 4).Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+    Text("0m")
+  svg.Add("text").
+    Num("x", label_width + 6 * cell_size + 4).Num("y", scale_y + 4).Attr("dominant-baseline", "middle").
+    Text(minutesFormatDuration(uint(math.Round(max_minutes))))
+
+  return svg
+}
+
+
+func activityRecordsHeatmapTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per weekday, with its minutes in each hour and its total.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  minutes := ActivityRecordsWeekdayHourMinutes(records)
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Time by weekday and hour"),
+    Columns: [] string { "Weekday" },
+  }
+  for hour := 0; hour < 24; hour++ {
+    table.Columns = append(table.Columns, fmt.Sprintf("%02d:00", hour))
+  }
+  table.Columns = append(table.Columns, "Total")
+
+  for weekday_i := range minutes {
+    row := [] string { time.Weekday((weekday_i + int(STT_WEEK_START)) % 7).String() }
+    weekday_minutes := 0.0
+    for hour := range minutes[weekday_i] {
+      row = append(row, chartTableMinutes(minutes[weekday_i][hour]))
+      weekday_minutes += minutes[weekday_i][hour]
+    }
+    table.Rows = append(table.Rows, append(row, chartTableMinutes(weekday_minutes)))
+  }
+  return table
+}
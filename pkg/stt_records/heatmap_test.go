@@ -0,0 +1,81 @@
+package stt_records;
+
+
+import (
+  "math"
+  "strings"
+  "testing"
+)
+
+
+func TestActivityRecordsWeekdayHourMinutes (t * testing.T) {
+  // 2026-03-02 is a Monday, the first row with the default STT_WEEK_START
+  type cell struct {
+    weekday_i, hour int;
+    minutes         float64;
+  }
+
+  tests := [] struct {
+    name   string;
+    record ActivityRecord;
+    want   [] cell;
+  } {
+    {
+      "within an hour",
+      testRecord("Email", "2026-03-02 09:10", "2026-03-02 09:40"),
+      [] cell { { 0, 9, 30 } },
+    },
+    {
+      "across hours",
+      testRecord("Email", "2026-03-02 09:45", "2026-03-02 11:15"),
+      [] cell { { 0, 9, 15 }, { 0, 10, 60 }, { 0, 11, 15 } },
+    },
+    {
+      "across midnight",
+      testRecord("Sleep", "2026-03-08 23:30", "2026-03-09 00:30"),
+      [] cell { { 6, 23, 30 }, { 0, 0, 30 } },
+    },
+    {
+      "no time",
+      testRecord("Email", "2026-03-02 09:00", "2026-03-02 09:00"),
+      [] cell {},
+    },
+  }
+
+  for _, test := range tests {
+    minutes := ActivityRecordsWeekdayHourMinutes([] ActivityRecord { test.record })
+
+    var want [7][24] float64
+    for _, cell := range test.want {
+      want[cell.weekday_i][cell.hour] = cell.minutes
+    }
+    for weekday_i := range want {
+      for hour := range want[weekday_i] {
+        if math.Abs(minutes[weekday_i][hour] - want[weekday_i][hour]) > 1e-9 {
+          t.Errorf(
+            "%s: weekday %d, hour %d: got %g minutes, want %g",
+            test.name, weekday_i, hour, minutes[weekday_i][hour], want[weekday_i][hour],
+          )
+        }
+      }
+    }
+  }
+}
+
+
+func TestActivityRecordsHeatmapSvgTheme (t * testing.T) {
+  records := [] ActivityRecord {
+    testRecord("Email", "2026-03-02 09:00", "2026-03-02 10:00"),
+  }
+
+  for _, theme := range [] string { "light", "dark" } {
+    svg  := testChartSvg(t, "heatmap", records, & ActivityRecordChartOptions { Theme: theme })
+    fill := ColorVariant(HEATMAP_CHART_FILL, theme)
+    if ! strings.Contains(svg, `fill="` + fill + `" fill-opacity="1"`) {
+      t.Errorf("%s: no cell filled %s in\n%s", theme, fill, svg)
+    }
+    if theme == "dark" && strings.Contains(svg, HEATMAP_CHART_FILL) {
+      t.Errorf("dark: the light theme's %s in\n%s", HEATMAP_CHART_FILL, svg)
+    }
+  }
+}
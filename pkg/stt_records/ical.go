@@ -0,0 +1,110 @@
+package stt_records;
+
+
+import (
+  "bufio"
+  "fmt"
+  "io"
+  "strings"
+  "time"
+  "unicode/utf8"
+)
+
+
+const ICAL_TIME_LAYOUT string = "20060102T150405Z"
+const ICAL_LINE_OCTETS int    = 75
+
+
+func icalEscapeText (text string) string {
+  /*
+    Escape a TEXT value, per RFC 5545 section 3.3.11.
+  */
+  text = strings.ReplaceAll(text, `\`, `\\`)
+  text = strings.ReplaceAll(text, ";", `\;`)
+  text = strings.ReplaceAll(text, ",", `\,`)
+  text = strings.ReplaceAll(text, "\r\n", `\n`)
+  text = strings.ReplaceAll(text, "\n", `\n`)
+  return text
+}
+
+
+func icalWriteLine (writer * bufio.Writer, line string) {
+  /*
+    Write a content line, folded so that no line is longer than 75 octets.
+    Folds don't split UTF-8 sequences.
+  */
+
+  limit := ICAL_LINE_OCTETS
+  for len(line) > limit {
+    fold_i := limit
+    for fold_i > 0 && ! utf8.RuneStart(line[fold_i]) {
+      fold_i--
+    }
+    writer.WriteString(line[:fold_i])
+    writer.WriteString("\r\n ")
+    line  = line[fold_i:]
+    limit = ICAL_LINE_OCTETS - 1  // the leading space of the fold counts
+  }
+  writer.WriteString(line)
+  writer.WriteString("\r\n")
+}
+
+
+func ActivityRecordsWriteIcal (io_writer io.Writer, records [] ActivityRecord, calendar_name string) error {
+  /*
+    Write records as an iCalendar feed with one VEVENT per record; the
+    activity name is the summary, the comment the description, and the
+    categories the event's CATEGORIES.
+  */
+
+  writer  := bufio.NewWriter(io_writer)
+  dtstamp := time.Now().UTC().Format(ICAL_TIME_LAYOUT)
+
+  icalWriteLine(writer, "BEGIN:VCALENDAR")
+  icalWriteLine(writer, "VERSION:2.0")
+  icalWriteLine(writer, "PRODID:-//gill-dashboard//STT records//EN")
+  icalWriteLine(writer, "CALSCALE:GREGORIAN")
+  icalWriteLine(writer, "METHOD:PUBLISH")
+  if calendar_name != "" {
+    icalWriteLine(writer, "X-WR-CALNAME:" + icalEscapeText(calendar_name))
+  }
+
+  // Records have no IDs of their own, so UIDs are made from their start time,
+  // the same way that snapshots are diffed. They stay stable across syncs as
+  // long as the record's start time does.
+
+  seen := make(map [string] int)
+
+  for _, record := range records {
+    start_str := record.Time_started.UTC().Format(ICAL_TIME_LAYOUT)
+    uid       := fmt.Sprintf("%s-%d@gill-dashboard", start_str, seen[start_str])
+    seen[start_str]++
+
+    icalWriteLine(writer, "BEGIN:VEVENT")
+    icalWriteLine(writer, "UID:" + uid)
+    icalWriteLine(writer, "DTSTAMP:" + dtstamp)
+    icalWriteLine(writer, "DTSTART:" + start_str)
+    icalWriteLine(writer, "DTEND:" + record.Time_ended.UTC().Format(ICAL_TIME_LAYOUT))
+    icalWriteLine(writer, "SUMMARY:" + icalEscapeText(record.Activity_name))
+
+    if record.Comment != "" {
+      icalWriteLine(writer, "DESCRIPTION:" + icalEscapeText(record.Comment))
+    }
+
+    categories := make([] string, 0, len(record.Categories))
+    for _, category := range record.Categories {
+      if category != "" {
+        categories = append(categories, icalEscapeText(category))
+      }
+    }
+    if len(categories) > 0 {
+      icalWriteLine(writer, "CATEGORIES:" + strings.Join(categories, ","))
+    }
+
+    icalWriteLine(writer, "TRANSP:TRANSPARENT")
+    icalWriteLine(writer, "END:VEVENT")
+  }
+
+  icalWriteLine(writer, "END:VCALENDAR")
+  return writer.Flush()
+}
@@ -0,0 +1,259 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+
+func chartRollingAverage (values [] float64, days int) [] float64 {
+  /*
+    Each value's mean with the days-1 values before it, or with as many as
+    there are, for the first few.
+  */
+
+  averages := make([] float64, len(values))
+  sum      := 0.0
+  for value_i, value := range values {
+    sum += value
+    if value_i >= days {
+      sum -= values[value_i - days]
+    }
+    averages[value_i] = sum / math.Min(float64(value_i + 1), float64(days))
+  }
+  return averages
+}
+
+
+func lineChartSeries (options * ActivityRecordChartOptions) (series_key string, windows [] int) {
+  /*
+    The key the lines are split by, categories by default, and the rolling
+    averages' windows, shortest first.
+  */
+  series_key = "category"
+  if options.Group_by != "" {
+    series_key = chartSeriesKey(options)
+  }
+  for _, window := range options.Rolling_days {
+    if window > 1 { windows = append(windows, window) }
+  }
+  sort.Ints(windows)
+  return series_key, windows
+}
+
+
+func lineChartTitle (series_key string, windows [] int) string {
+  title := "Time per day by " + series_key
+  if len(windows) > 0 {
+    // "7-day rolling average", or "7-, 14- and 28-day rolling averages"
+    windows_str := strconv.Itoa(windows[len(windows)-1])
+    averages_str := "average"
+    if len(windows) > 1 {
+      window_strs := make([] string, len(windows) - 1)
+      for window_i := range window_strs {
+        window_strs[window_i] = strconv.Itoa(windows[window_i])
+      }
+      windows_str  = strings.Join(window_strs, "-, ") + "- and " + windows_str
+      averages_str = "averages"
+    }
+    title = fmt.Sprintf("Time per day by %s, %s-day rolling %s", series_key, windows_str, averages_str)
+  }
+  return title
+}
+
+
+func activityRecordsLineChartSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot each category's (or activity's or tag's, per the Group_by option)
+    daily total, from the first record's day to the final record's, as a line.
+    With Rolling_days set (say 7 and 28), the lines are rolling averages over
+    each many days, drawn over the faint daily totals, the longest boldest.
+  */
+
+  options = svgChartOptions(options, "720", "320")
+  series_key, windows := lineChartSeries(options)
+  rolling := len(windows) > 0
+
+  // Series, largest first, and each one's minutes per day, including the
+  // days without any
+
+  series_groups, days, daily := activityRecordsDailySeries(records, series_key)
+
+  // averages[window_i][series_i] are the series' rolling averages over the
+  // window's days
+  averages := make([][][] float64, len(windows))
+  for window_i, window := range windows {
+    averages[window_i] = make([][] float64, len(daily))
+    for series_i := range daily {
+      averages[window_i][series_i] = chartRollingAverage(daily[series_i], window)
+    }
+  }
+
+  max_minutes := 0.0
+  for series_i := range daily {
+    for _, minutes := range daily[series_i] {
+      max_minutes = math.Max(max_minutes, minutes)
+    }
+  }
+
+  // Geometry, in viewBox units: the plot, with axis labels to its left and
+  // below, and the legend to its right
+
+  const plot_left     float64 = 40
+  const plot_top      float64 = 20
+  const plot_width    float64 = 540
+  const plot_height   float64 = 240
+  const legend_left   float64 = plot_left + plot_width + 20
+  const legend_width  float64 = 140
+  const legend_row    float64 = 16
+
+  view_height := math.Max(plot_top + plot_height + 30, plot_top + float64(len(series_groups)) * legend_row)
+
+  ticks     := chartNiceTicks(max_minutes / 60, 5)
+  max_hours := ticks[len(ticks)-1]
+  x := func (day_i int) float64 {
+    if len(days) < 2 {
+      return plot_left + plot_width / 2
+    }
+    return plot_left + plot_width * float64(day_i) / float64(len(days) - 1)
+  }
+  y := func (minutes float64) float64 {
+    return plot_top + plot_height - plot_height * (minutes / 60) / max_hours
+  }
+
+  title := lineChartTitle(series_key, windows)
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, legend_left + legend_width, view_height },
+    title, chartDescribeRecords(records, series_groups),
+    "    polyline { fill: none; stroke-width: 1.5; stroke-linejoin: round; }\n" +
+    "    polyline.daily { stroke-width: 0.75; }\n" +
+    "    polyline.faint { stroke-opacity: 0.3; }\n" +
+    "    polyline.short { stroke-width: 1; stroke-opacity: 0.6; }\n" +
+    "    polyline:hover { stroke-width: 2.5; }\n" +
+    "    line.grid { stroke: #8884; stroke-width: 0.5; }\n" +
+    "    line.axis { stroke: #888; stroke-width: 1; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n",
+  )
+
+  // Gridlines and y-axis labels
+
+  for _, tick := range ticks {
+    tick_y := y(tick * 60)
+    svg.Add("line").Attr("class", "grid").
+      Num("x1", plot_left).Num("y1", tick_y).Num("x2", plot_left + plot_width).Num("y2", tick_y)
+    svg.Add("text").
+      Num("x", plot_left - 4).Num("y", tick_y).Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+      Text(chartFormatHours(tick))
+  }
+
+  // x-axis labels, about eight of them, spread evenly
+
+  label_every := int(math.Ceil(float64(len(days)) / 8))
+  for day_i := 0; day_i < len(days); day_i += label_every {
+    svg.Add("text").
+      Num("x", x(day_i)).Num("y", plot_top + plot_height + 12).Attr("text-anchor", "middle").
+      Text(days[day_i].Format("2 Jan 06"))
+  }
+
+  // Lines, the largest series drawn last, over the others
+
+  addLine := func (class, fill, title string, values [] float64) {
+    coordinates := make([] float64, 0, 2 * len(values))
+    for day_i, minutes := range values {
+      coordinates = append(coordinates, x(day_i), y(minutes))
+    }
+    svg.Add("polyline").Attr("class", class).
+      Points("points", coordinates...).
+      Attr("stroke", fill).
+      Title(title)
+  }
+
+  for series_i := len(series_groups) - 1; series_i >= 0; series_i-- {
+    name := series_groups[series_i].Keys[0]
+    fill := chartColor(options, name)
+    if ! rolling {
+      addLine("daily", fill, name, daily[series_i])
+      continue
+    }
+    addLine("daily faint", fill, name + ", daily", daily[series_i])
+    for window_i, window := range windows {
+      class := "average"
+      if window_i < len(windows) - 1 {
+        class = "average short"
+      }
+      addLine(class, fill, fmt.Sprintf("%s, %d-day average", name, window), averages[window_i][series_i])
+    }
+  }
+
+  // Axes
+
+  svg.Add("line").Attr("class", "axis").
+    Num("x1", plot_left).Num("y1", plot_top).Num("x2", plot_left).Num("y2", plot_top + plot_height)
+  svg.Add("line").Attr("class", "axis").
+    Num("x1", plot_left).Num("y1", plot_top + plot_height).Num("x2", plot_left + plot_width).Num("y2", plot_top + plot_height)
+
+  // Legend
+
+  for series_i, series := range series_groups {
+    row_y := plot_top + float64(series_i) * legend_row
+    svg.Add("rect").
+      Num("x", legend_left).Num("y", row_y).Num("width", 10).Num("height", 10).
+      Attr("fill", chartColor(options, series.Keys[0]))
+    svg.Add("text").
+      Num("x", legend_left + 14).Num("y", row_y + 5).Attr("dominant-baseline", "middle").
+      Text(fmt.Sprintf("%s (%s)", series.Keys[0], minutesFormatDuration(series.Minutes)))
+  }
+
+  return svg
+}
+
+
+func activityRecordsLineChartTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per day, with each series' time, and its rolling averages if the
+    chart has them, each line's points.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  series_key, windows := lineChartSeries(options)
+  series_groups, days, daily := activityRecordsDailySeries(records, series_key)
+
+  averages := make([][][] float64, len(windows))
+  for window_i, window := range windows {
+    averages[window_i] = make([][] float64, len(daily))
+    for series_i := range daily {
+      averages[window_i][series_i] = chartRollingAverage(daily[series_i], window)
+    }
+  }
+
+  table := & ChartTable {
+    Caption: chartTitle(options, lineChartTitle(series_key, windows)),
+    Columns: [] string { "Day" },
+  }
+  for _, series := range series_groups {
+    table.Columns = append(table.Columns, series.Keys[0])
+    for _, window := range windows {
+      table.Columns = append(table.Columns, fmt.Sprintf("%s, %d-day average", series.Keys[0], window))
+    }
+  }
+
+  for day_i, day := range days {
+    row := [] string { day.Format(STT_DATE_LAYOUT) }
+    for series_i := range series_groups {
+      row = append(row, chartTableMinutes(daily[series_i][day_i]))
+      for window_i := range windows {
+        row = append(row, chartTableMinutes(averages[window_i][series_i][day_i]))
+      }
+    }
+    table.Rows = append(table.Rows, row)
+  }
+  return table
+}
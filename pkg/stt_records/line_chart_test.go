@@ -0,0 +1,72 @@
+package stt_records;
+
+
+import (
+  "math"
+  "strings"
+  "testing"
+)
+
+
+func TestChartRollingAverage (t * testing.T) {
+  tests := [] struct {
+    values [] float64;
+    days   int;
+    want   [] float64;
+  } {
+    { [] float64 {},                 7, [] float64 {} },
+    { [] float64 { 60, 0, 30 },      1, [] float64 { 60, 0, 30 } },
+    { [] float64 { 60, 0, 30, 90 },  2, [] float64 { 60, 30, 15, 60 } },
+    { [] float64 { 60, 0, 30, 90 },  3, [] float64 { 60, 30, 30, 40 } },
+    { [] float64 { 70, 0 },          7, [] float64 { 70, 35 } },
+  }
+
+  for _, test := range tests {
+    got := chartRollingAverage(test.values, test.days)
+    if len(got) != len(test.want) {
+      t.Errorf("%v over %d days: got %v, want %v", test.values, test.days, got, test.want)
+      continue
+    }
+    for value_i := range got {
+      if math.Abs(got[value_i] - test.want[value_i]) > 1e-9 {
+        t.Errorf("%v over %d days: got %v, want %v", test.values, test.days, got, test.want)
+        break
+      }
+    }
+  }
+}
+
+
+func TestActivityRecordsLineChartSvgRolling (t * testing.T) {
+  records := [] ActivityRecord {
+    testRecord("Email",  "2026-03-02 09:00", "2026-03-02 10:00"),
+    testRecord("Coding", "2026-03-05 10:00", "2026-03-05 12:00"),
+  }
+  records[0].Categories = [] string { "Communication" }
+  records[1].Categories = [] string { "Development" }
+
+  tests := [] struct {
+    rolling_days [] int;
+    title        string;
+    averages     int;  // average lines per series
+  } {
+    { nil,                  "Time per day by category",                                    0 },
+    { [] int { 1 },         "Time per day by category",                                    0 },
+    { [] int { 7 },         "Time per day by category, 7-day rolling average",             1 },
+    { [] int { 28, 7 },     "Time per day by category, 7- and 28-day rolling averages",    2 },
+    { [] int { 7, 14, 28 }, "Time per day by category, 7-, 14- and 28-day rolling averages", 3 },
+  }
+
+  for _, test := range tests {
+    svg := testChartSvg(t, "lines", records, & ActivityRecordChartOptions { Rolling_days: test.rolling_days })
+    if ! strings.Contains(svg, "<title>" + test.title + "</title>") {
+      t.Errorf("%v: no title %q in\n%s", test.rolling_days, test.title, svg)
+    }
+    if got := strings.Count(svg, `class="average`); got != 2 * test.averages {
+      t.Errorf("%v: %d average lines, want %d", test.rolling_days, got, 2 * test.averages)
+    }
+    if got := strings.Count(svg, `class="average"`); test.averages > 0 && got != 2 {
+      t.Errorf("%v: %d bold average lines, want 2", test.rolling_days, got)
+    }
+  }
+}
@@ -0,0 +1,641 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strconv"
+  "strings"
+)
+
+
+const PIE_CHART_OTHER      string = "Other"
+const PIE_CHART_OTHER_FILL string = "#bbbbbb"
+
+
+func ValidatePieOrder (order string) error {
+  switch order {
+  case "", "size", "name":
+    return nil
+  }
+  return fmt.Errorf("unknown slice order \"%s\", expected size or name", order)
+}
+
+
+func ParsePieTargets (targets_str string) (map [string] float64, error) {
+  /*
+    Parse comma-separated "Activity=weight" pairs, for the pie chart's target
+    ring. Weights are relative to one another, so they can be percentages or
+    hours.
+  */
+
+  targets := make(map [string] float64)
+  for _, pair := range strings.Split(targets_str, ",") {
+    if strings.TrimSpace(pair) == "" { continue }
+
+    name, weight_str, found := strings.Cut(pair, "=")
+    name = strings.TrimSpace(name)
+    if ! found || name == "" {
+      return nil, fmt.Errorf("invalid target \"%s\", expected Activity=weight", pair)
+    }
+
+    weight, err := strconv.ParseFloat(strings.TrimSpace(weight_str), 64)
+    if err != nil || weight < 0 || math.IsInf(weight, 0) {
+      return nil, fmt.Errorf("invalid target weight \"%s\" for \"%s\"", weight_str, name)
+    }
+    targets[name] = weight
+  }
+  return targets, nil
+}
+
+
+type pieSlice struct {
+  name       string;
+  title      string;
+  minutes      uint;
+  weight    float64;
+  ratio     float64;
+  angle     float64;
+  center_t  float64;
+
+  start_t    float64;
+  end_t      float64;
+
+  fill       string;
+}
+
+
+func pieLayoutSlices (slices [] pieSlice) [] pieSlice {
+  /*
+    Set slices' ratios of their total weight, and their angles, clockwise
+    from the positive x axis.
+  */
+
+  total := 0.0
+  for _, slice := range slices {
+    total += slice.weight
+  }
+
+  var pie_head float64 = 0  // keep track of the angle as we create slices
+  for slice_i := range slices {
+    slice := &slices[slice_i]
+    if total > 0 {
+      slice.ratio = slice.weight / total
+    }
+    slice.start_t   = pie_head
+    slice.angle     = 2 * math.Pi * slice.ratio
+    slice.end_t     = pie_head + slice.angle
+
+    slice.center_t  = slice.start_t + slice.angle/2
+
+    pie_head        = slice.end_t
+  }
+  return slices
+}
+
+
+func pieFoldSlices (
+  groups    [] AggregateGroup,
+  names     [] string,
+  options    * ActivityRecordChartOptions,
+) [] pieSlice {
+  /*
+    A slice for each of the named activities' groups, in that order, and one
+    more for the rest of them, as "Other" (or as itself, if there's only one).
+  */
+
+  minutes := make(map [string] uint, len(groups))
+  for _, group := range groups {
+    minutes[group.Keys[0]] = group.Minutes
+  }
+
+  slices := make([] pieSlice, 0, len(names) + 1)
+  kept   := make(map [string] bool, len(names))
+  for _, name := range names {
+    kept[name] = true
+    if minutes[name] == 0 { continue }
+    slices = append(slices, pieSlice {
+      name:    name,
+      title:   name,
+      minutes: minutes[name],
+      weight:  float64(minutes[name]),
+      fill:    chartColor(options, name),
+    })
+  }
+
+  other := pieSlice {
+    name: PIE_CHART_OTHER,
+    fill: ColorVariant(PIE_CHART_OTHER_FILL, options.Theme),
+  }
+  var other_names [] string
+  for _, group := range groups {
+    if kept[group.Keys[0]] { continue }
+    other.minutes += group.Minutes
+    other_names    = append(other_names, group.Keys[0])
+  }
+
+  // A lone small activity is clearer as itself than as "Other"
+  if len(other_names) == 1 {
+    other.name = other_names[0]
+    other.fill = chartColor(options, other.name)
+  }
+  if other.minutes > 0 {
+    other.title  = other.name
+    other.weight = float64(other.minutes)
+    if len(other_names) > 1 {
+      other.title += ": " + strings.Join(other_names, ", ")
+    }
+    slices = append(slices, other)
+  }
+
+  return pieLayoutSlices(slices)
+}
+
+
+type pieLabel struct {
+  slice_i int;
+  y       float64;
+}
+
+
+func pieLayoutLabels (labels [] pieLabel, gap, top, bottom float64) {
+  /*
+    Spread labels on one side of the pie so that they're at least gap apart,
+    moving them as little as it can from where they'd like to be, between top
+    and bottom where there's room for them all.
+  */
+
+  sort.SliceStable(labels, func (i, j int) bool { return labels[i].y < labels[j].y })
+
+  for label_i := range labels {
+    min_y := top
+    if label_i > 0 {
+      min_y = labels[label_i - 1].y + gap
+    }
+    labels[label_i].y = math.Max(labels[label_i].y, min_y)
+  }
+
+  for label_i := len(labels) - 1; label_i >= 0; label_i-- {
+    max_y := bottom
+    if label_i < len(labels) - 1 {
+      max_y = labels[label_i + 1].y - gap
+    }
+    labels[label_i].y = math.Max(math.Min(labels[label_i].y, max_y), top)
+  }
+}
+
+
+func pieFitLabels (labels [] pieLabel, pie [] pieSlice, count int) ([] pieLabel, bool) {
+  /*
+    The labels of the count largest slices, if there are more labels than
+    that, and whether any were left out.
+  */
+
+  if len(labels) <= count {
+    return labels, false
+  }
+  fitted := append([] pieLabel (nil), labels...)
+  sort.SliceStable(fitted, func (i, j int) bool {
+    return pie[fitted[i].slice_i].minutes > pie[fitted[j].slice_i].minutes
+  })
+  return fitted[:count], true
+}
+
+
+func ActivityRecordsPlotPieChart (records [] ActivityRecord, options * ActivityRecordChartOptions) string {
+  return activityRecordsPieSvg(records, options).String()
+}
+
+
+type pieRings struct {
+  series_key       string;
+  pie              [] pieSlice;
+  compare_pie      [] pieSlice;
+  target_pie       [] pieSlice;
+  records_duration uint;
+  compare_duration uint;
+}
+
+
+func activityRecordsPieRings (records [] ActivityRecord, options * ActivityRecordChartOptions) pieRings {
+  //
+  // Get sums of minutes for each activity name (or category or tag, per the
+  // Group_by option), and calculate their "pie slice" ratio by dividing them
+  // by the sum of all their minutes across records.
+  //
+
+  series_key := chartSeriesKey(options)
+
+  activity_groups, _ := ActivityRecordsAggregate(records, series_key)
+  var records_duration uint = 0
+
+  for _, group := range activity_groups {
+    records_duration += group.Minutes
+  }
+
+  if options.Order != "name" {
+    AggregateGroupsSortBySum(activity_groups)
+  }
+
+  //
+  // Generate activity pie slice geometry. Activities under Other_percent of
+  // the total are folded into one slice, after the others.
+  //
+
+  var names [] string
+  for _, group := range activity_groups {
+    if 100 * float64(group.Minutes) >= options.Other_percent * float64(records_duration) {
+      names = append(names, group.Keys[0])
+    }
+  }
+
+  pie := pieFoldSlices(activity_groups, names, options)
+
+  // The comparison ring has the same slices, in the same order, with
+  // anything else folded into its "Other"
+
+  var compare_pie [] pieSlice
+  var compare_duration uint = 0
+  if options.Compare_records != nil {
+    compare_groups, _ := ActivityRecordsAggregate(options.Compare_records, series_key)
+    for _, group := range compare_groups {
+      compare_duration += group.Minutes
+    }
+    compare_pie = pieFoldSlices(compare_groups, names, options)
+  }
+
+  // The target ring has the targeted activities in the pie's order, then any
+  // others by name
+
+  var target_pie [] pieSlice
+  if len(options.Targets) > 0 {
+    target_names := make([] string, 0, len(options.Targets))
+    for name := range options.Targets {
+      target_names = append(target_names, name)
+    }
+    pie_order := make(map [string] int, len(activity_groups))
+    for group_i, group := range activity_groups {
+      pie_order[group.Keys[0]] = group_i + 1
+    }
+    sort.Slice(target_names, func (i, j int) bool {
+      order_i, order_j := pie_order[target_names[i]], pie_order[target_names[j]]
+      if order_i != order_j {
+        return order_j == 0 || (order_i != 0 && order_i < order_j)
+      }
+      return target_names[i] < target_names[j]
+    })
+
+    for _, name := range target_names {
+      if options.Targets[name] <= 0 { continue }
+      target_pie = append(target_pie, pieSlice {
+        name:   name,
+        title:  name,
+        weight: options.Targets[name],
+        fill:   chartColor(options, name),
+      })
+    }
+    target_pie = pieLayoutSlices(target_pie)
+  }
+
+  return pieRings { series_key, pie, compare_pie, target_pie, records_duration, compare_duration }
+}
+
+
+func activityRecordsPieSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  rings := activityRecordsPieRings(records, options)
+  series_key, pie, compare_pie, target_pie := rings.series_key, rings.pie, rings.compare_pie, rings.target_pie
+  records_duration, compare_duration := rings.records_duration, rings.compare_duration
+
+  //
+  // Rings, from the inside out: a hole for a donut or a comparison, the
+  // comparison ring, the pie's ring, and the target ring
+  //
+
+  hole_r, pie_inner_r := 0.0, 0.0
+  compare_inner_r, compare_outer_r := 0.0, 0.0
+  if compare_pie != nil {
+    hole_r          = 0.38
+    compare_inner_r = hole_r
+    compare_outer_r = 0.66
+    pie_inner_r     = 0.7
+  } else if options.Donut {
+    hole_r      = 0.55
+    pie_inner_r = hole_r
+  }
+  outer_r := 1.0
+  if target_pie != nil {
+    outer_r = 1.14
+  }
+
+  //
+  // Lay out the slice labels in a column either side of the pie, each as
+  // near its slice's middle as it can be without overlapping its
+  // neighbours. When a side has more labels than fit at their usual
+  // spacing, they're made smaller, down to 70% of their size, and past that
+  // the smallest slices' labels are left to the legend.
+  //
+
+  const label_gap     float64 = 0.2
+  const label_gap_min float64 = 0.14
+  const label_top     float64 = -0.98
+  const label_bottom  float64 = 0.95
+  label_count_max := int(math.Floor((label_bottom - label_top) / label_gap_min)) + 1
+
+  var left_labels, right_labels [] pieLabel
+  for slice_i, slice := range pie {
+    label := pieLabel { slice_i, math.Sin(slice.center_t) * 1.15 }
+    if math.Cos(slice.center_t) < 0 {
+      left_labels = append(left_labels, label)
+    } else {
+      right_labels = append(right_labels, label)
+    }
+  }
+  left_labels,  left_folded  := pieFitLabels(left_labels,  pie, label_count_max)
+  right_labels, right_folded := pieFitLabels(right_labels, pie, label_count_max)
+  legend := options.Legend || left_folded || right_folded
+
+  gap := label_gap
+  if side_count := max(len(left_labels), len(right_labels)); side_count > 1 {
+    gap = math.Min(label_gap, (label_bottom - label_top) / float64(side_count - 1))
+  }
+  label_scale := gap / label_gap
+  pieLayoutLabels(left_labels,  gap, label_top, label_bottom)
+  pieLayoutLabels(right_labels, gap, label_top, label_bottom)
+
+  labelText := func (slice pieSlice) string {
+    return fmt.Sprintf("%s (%2.1f%%)", slice.name, slice.ratio * 100)
+  }
+  legendText := func (slice pieSlice) string {
+    return fmt.Sprintf("%s %2.1f%% (%s)", slice.name, slice.ratio * 100, minutesFormatDuration(slice.minutes))
+  }
+  labelsWidth := func (labels [] pieLabel) (width float64) {
+    for _, label := range labels {
+      slice := pie[label.slice_i]
+      width = math.Max(width, chartTextWidth(labelText(slice), 0.1 * label_scale))
+      width = math.Max(width, chartTextWidth(minutesFormatDuration(slice.minutes), 0.08 * label_scale))
+    }
+    return width
+  }
+
+  //
+  // Size the view box to the pie and its widest labels, with room to the
+  // right for a legend if there is one, and default the chart's size to
+  // 100 pixels to the pie's radius
+  //
+
+  const view_margin float64 = 0.05
+  const legend_row  float64 = 0.13
+
+  label_x    := outer_r + 0.3
+  view_left  := -math.Max(2, label_x + labelsWidth(left_labels)  + view_margin)
+  view_right :=  math.Max(2, label_x + labelsWidth(right_labels) + view_margin)
+  view_top, view_height := -1.1, 2.2
+
+  legend_left := view_right + 0.1
+  if legend {
+    legend_width := 0.0
+    for _, slice := range pie {
+      legend_width = math.Max(legend_width, 0.12 + chartTextWidth(legendText(slice), 0.08))
+    }
+    view_right  = legend_left + legend_width + view_margin
+    view_height = math.Max(view_height, float64(len(pie)) * legend_row + 0.2)
+  }
+  view_width := view_right - view_left
+
+  options = svgChartOptions(
+    options,
+    strconv.Itoa(int(math.Round(100 * view_width))), strconv.Itoa(int(math.Round(100 * view_height))),
+  )
+
+  // The description has the slices, the comparison's total, and the targets
+
+  compare_label := options.Compare_label
+  if compare_label == "" {
+    compare_label = "Previous"
+  }
+  actual_ratios := make(map [string] float64, len(pie))
+  for _, slice := range pie {
+    actual_ratios[slice.name] = slice.ratio
+  }
+
+  var description strings.Builder
+  if records_duration == 0 {
+    description.WriteString("No records.")
+  } else {
+    slice_descriptions := make([] string, len(pie))
+    for slice_i, slice := range pie {
+      slice_descriptions[slice_i] = fmt.Sprintf("%s %s (%.1f%%)", slice.name, minutesFormatDuration(slice.minutes), slice.ratio * 100)
+    }
+    fmt.Fprintf(
+      &description, "%s %s: %s.",
+      minutesFormatDuration(records_duration), chartDescribeSpan(records), strings.Join(slice_descriptions, ", "),
+    )
+  }
+  if compare_pie != nil {
+    fmt.Fprintf(&description, " %s: %s.", compare_label, minutesFormatDuration(compare_duration))
+  }
+  if len(target_pie) > 0 {
+    target_descriptions := make([] string, len(target_pie))
+    for slice_i, slice := range target_pie {
+      target_descriptions[slice_i] = fmt.Sprintf(
+        "%s %.1f%% (actually %.1f%%)", slice.name, slice.ratio * 100, actual_ratios[slice.name] * 100,
+      )
+    }
+    fmt.Fprintf(&description, " Targets: %s.", strings.Join(target_descriptions, ", "))
+  }
+
+  pie_svg := svgChartRoot(
+    options,
+    [4] float64 { view_left, view_top, view_width, view_height },
+    "Time by " + series_key, description.String(),
+    "    path { transition: all 0.25s; stroke-width: 0.01; stroke: #8880; }\n" +
+    "    path:hover { transform: scale(1.075); filter: brightness(1.1); stroke-width: 0.01; stroke: #8888; }\n" +
+    "    path.compare { fill-opacity: 0.75; }\n" +
+    "    path.target { fill-opacity: 0.6; }\n" +
+    "    polyline.leader { fill: none; stroke: #888; stroke-width: 0.006; }\n" +
+    "    text { font-family: sans-serif; }\n",
+  )
+  pie_svg.Precision = 4
+
+  //
+  // Generate SVG slice paths
+  //
+
+  for _, slice := range pie {
+    pie_svg.Add("path").
+      Path(svgArcPath(slice.start_t, slice.end_t, pie_inner_r, 1)).
+      Attr("fill", slice.fill).
+      Title(fmt.Sprintf("%s: %s, %2.1f%%", slice.title, minutesFormatDuration(slice.minutes), slice.ratio * 100))
+  }
+
+  for _, slice := range compare_pie {
+    pie_svg.Add("path").
+      Attr("class", "compare").
+      Path(svgArcPath(slice.start_t, slice.end_t, compare_inner_r, compare_outer_r)).
+      Attr("fill", slice.fill).
+      Title(fmt.Sprintf(
+        "%s, %s: %s, %2.1f%%",
+        compare_label, slice.title, minutesFormatDuration(slice.minutes), slice.ratio * 100,
+      ))
+  }
+
+  for _, slice := range target_pie {
+    pie_svg.Add("path").
+      Attr("class", "target").
+      Path(svgArcPath(slice.start_t, slice.end_t, 1.04, outer_r)).
+      Attr("fill", slice.fill).
+      Title(fmt.Sprintf(
+        "Target, %s: %2.1f%% (actually %2.1f%%)",
+        slice.title, slice.ratio * 100, actual_ratios[slice.name] * 100,
+      ))
+  }
+
+  // The total, and the comparison's, in the hole
+
+  if hole_r > 0 {
+    pie_svg.Add("text").
+      Num("x", 0).Num("y", 0).Num("font-size", 0.14).Attr("text-anchor", "middle").
+      Text(minutesFormatDuration(records_duration))
+    if compare_pie != nil {
+      pie_svg.Add("text").
+        Num("x", 0).Num("y", 0.1).Num("font-size", 0.05).Attr("text-anchor", "middle").
+        Text(compare_label)
+      pie_svg.Add("text").
+        Num("x", 0).Num("y", 0.18).Num("font-size", 0.07).Attr("text-anchor", "middle").
+        Text(minutesFormatDuration(compare_duration))
+    }
+  }
+
+  //
+  // Draw the labels, with a leader line from each slice to its label
+  //
+
+  addLabels := func (labels [] pieLabel, side float64, anchor string) {
+    for _, label := range labels {
+      slice  := pie[label.slice_i]
+      text_x := side * label_x
+
+      pie_svg.Add("polyline").
+        Attr("class", "leader").
+        Points(
+          "points",
+          math.Cos(slice.center_t) * 1.01, math.Sin(slice.center_t) * 1.01,
+          math.Cos(slice.center_t) * (outer_r + 0.1), math.Sin(slice.center_t) * (outer_r + 0.1),
+          text_x - side * 0.03, label.y - 0.035 * label_scale,
+        )
+
+      pie_svg.Add("text").
+        Num("x", text_x).Num("y", label.y).Num("font-size", 0.1 * label_scale).Attr("text-anchor", anchor).
+        Text(labelText(slice))
+
+      pie_svg.Add("text").
+        Num("x", text_x).Num("y", label.y + 0.1 * label_scale).Num("font-size", 0.08 * label_scale).Attr("text-anchor", anchor).
+        Text(minutesFormatDuration(slice.minutes))
+    }
+  }
+  addLabels(left_labels,  -1, "end")
+  addLabels(right_labels,  1, "start")
+
+  //
+  // Generate the legend, in slice order
+  //
+
+  if legend {
+    for slice_i, slice := range pie {
+      row_y := -1.0 + float64(slice_i) * legend_row
+      pie_svg.Add("rect").
+        Num("x", legend_left).Num("y", row_y).Num("width", 0.08).Num("height", 0.08).
+        Attr("fill", slice.fill)
+      pie_svg.Add("text").
+        Num("x", legend_left + 0.12).Num("y", row_y + 0.07).Num("font-size", 0.08).
+        Text(legendText(slice))
+    }
+  }
+
+  return pie_svg
+}
+
+
+func activityRecordsPieTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per slice of the pie, with its time and share, and those of the
+    same slice of the comparison, and its target share, if the chart has
+    them. Targets for anything not in the pie get rows of their own.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  rings := activityRecordsPieRings(records, options)
+
+  compare_label := options.Compare_label
+  if compare_label == "" {
+    compare_label = "Previous"
+  }
+  compare_slices := make(map [string] pieSlice, len(rings.compare_pie))
+  for _, slice := range rings.compare_pie {
+    compare_slices[slice.name] = slice
+  }
+  target_ratios := make(map [string] float64, len(rings.target_pie))
+  for _, slice := range rings.target_pie {
+    target_ratios[slice.name] = slice.ratio
+  }
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Time by " + rings.series_key),
+    Columns: [] string { chartTableHeading(rings.series_key), "Time", "Share" },
+  }
+  if rings.compare_pie != nil {
+    table.Columns = append(table.Columns, compare_label + " time", compare_label + " share")
+  }
+  if rings.target_pie != nil {
+    table.Columns = append(table.Columns, "Target share")
+  }
+
+  addRow := func (title string, slice pieSlice, in_pie bool) {
+    row := [] string { title, "", "" }
+    if in_pie {
+      row[1], row[2] = minutesFormatDuration(slice.minutes), chartTablePercent(slice.ratio * 100)
+    }
+    if rings.compare_pie != nil {
+      compare_slice, found := compare_slices[slice.name]
+      if found {
+        row = append(row, minutesFormatDuration(compare_slice.minutes), chartTablePercent(compare_slice.ratio * 100))
+      } else {
+        row = append(row, "", "")
+      }
+    }
+    if rings.target_pie != nil {
+      ratio, found := target_ratios[slice.name]
+      if found {
+        row = append(row, chartTablePercent(ratio * 100))
+      } else {
+        row = append(row, "")
+      }
+    }
+    table.Rows = append(table.Rows, row)
+  }
+
+  in_pie := make(map [string] bool, len(rings.pie))
+  for _, slice := range rings.pie {
+    in_pie[slice.name] = true
+    addRow(slice.title, slice, true)
+  }
+  for _, slice := range rings.target_pie {
+    if in_pie[slice.name] { continue }
+    addRow(slice.title, slice, false)
+  }
+
+  table.Footer = [] string { "Total", minutesFormatDuration(rings.records_duration), "" }
+  if rings.compare_pie != nil {
+    table.Footer = append(table.Footer, minutesFormatDuration(rings.compare_duration), "")
+  }
+  if rings.target_pie != nil {
+    table.Footer = append(table.Footer, "")
+  }
+  return table
+}
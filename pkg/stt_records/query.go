@@ -0,0 +1,329 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "strings"
+  "time"
+  "unicode"
+)
+
+
+//
+// A small query language for filtering records, e.g.:
+//
+//   category:Development -activity:Email tag:client=acme after:2024-01-01 comment~"refactor"
+//
+// A query is whitespace-separated terms, all of which must match. A term is
+// either a bare word, matching activity names and comments which contain it,
+// or FIELD OPERATOR VALUES:
+//
+//   activity, category, tag, comment   ":" or "=" (equal) or "~" (contains),
+//                                      case-insensitive
+//   after, before, on                  ":" and a date range expression (see
+//                                      ParseDateRange), e.g. after:2024-01-01
+//                                      or on:"last week"; inclusive
+//   duration                           ":" "<" "<=" ">" ">=" and a Go
+//                                      duration such as 30m or 1h30m
+//
+// Values can be double-quoted, and comma-separated values are alternatives:
+// category:Productivity,Development matches either. A leading "-" negates a
+// term.
+//
+
+
+var QUERY_FIELDS [] string = [] string {
+  "activity",
+  "category",
+  "tag",
+  "comment",
+  "after",
+  "before",
+  "on",
+  "duration",
+}
+
+
+type RecordQuery struct {
+  Source string;
+  terms  [] queryTerm;
+}
+
+
+type queryTerm struct {
+  negate bool;
+  field  string;  // empty for bare words
+  op     string;
+  values [] string;
+
+  ranges    [] DateRange;
+  durations [] time.Duration;
+}
+
+
+type queryScanner struct {
+  input [] rune;
+  pos   int;
+}
+
+
+func (scanner *queryScanner) done () bool {
+  return scanner.pos >= len(scanner.input)
+}
+
+
+func (scanner *queryScanner) peek () rune {
+  if scanner.done() { return 0 }
+  return scanner.input[scanner.pos]
+}
+
+
+func (scanner *queryScanner) skipSpace () {
+  for ! scanner.done() && unicode.IsSpace(scanner.peek()) {
+    scanner.pos++
+  }
+}
+
+
+func (scanner *queryScanner) scanValue () (value string, err error) {
+  /*
+    Scan a value up to whitespace or a comma. Double-quoted parts may contain
+    either, and backslash-escaped quotes and backslashes.
+  */
+
+  var builder strings.Builder
+  start := scanner.pos
+
+  for ! scanner.done() {
+    r := scanner.peek()
+    if unicode.IsSpace(r) || r == ',' { break }
+
+    if r != '"' {
+      builder.WriteRune(r)
+      scanner.pos++
+      continue
+    }
+
+    scanner.pos++  // opening quote
+    for {
+      if scanner.done() {
+        return "", fmt.Errorf("unterminated quote at position %d", start + 1)
+      }
+      r = scanner.peek()
+      scanner.pos++
+      if r == '"' { break }
+      if r == '\\' && ! scanner.done() {
+        r = scanner.peek()
+        scanner.pos++
+      }
+      builder.WriteRune(r)
+    }
+  }
+
+  return builder.String(), nil
+}
+
+
+func (scanner *queryScanner) scanFieldOp () (field, op string) {
+  /*
+    Scan FIELD OPERATOR, if the input continues with one; otherwise leave the
+    position where it was.
+  */
+
+  start := scanner.pos
+  for ! scanner.done() && (unicode.IsLetter(scanner.peek()) || scanner.peek() == '_') {
+    scanner.pos++
+  }
+  field = strings.ToLower(string(scanner.input[start:scanner.pos]))
+
+  for _, candidate := range [] string { ">=", "<=", ":", "=", "~", ">", "<" } {
+    candidate_runes := [] rune(candidate)
+    end := scanner.pos + len(candidate_runes)
+    if field != "" && end <= len(scanner.input) && string(scanner.input[scanner.pos:end]) == candidate {
+      scanner.pos = end
+      return field, candidate
+    }
+  }
+
+  scanner.pos = start
+  return "", ""
+}
+
+
+func parseQueryTerm (term * queryTerm) (err error) {
+  /*
+    Validate a term's field and operator, and parse its values.
+  */
+
+  switch term.field {
+  case "":
+    term.op = "~"
+
+  case "activity", "category", "tag", "comment":
+    if term.op == "=" { term.op = ":" }
+    if term.op != ":" && term.op != "~" {
+      return fmt.Errorf("%s takes \":\" or \"~\", not \"%s\"", term.field, term.op)
+    }
+
+  case "after", "before", "on":
+    if term.op != ":" && term.op != "=" {
+      return fmt.Errorf("%s takes \":\", not \"%s\"", term.field, term.op)
+    }
+    for _, value := range term.values {
+      date_range, err := ParseDateRange(value, time.Now())
+      if err != nil { return fmt.Errorf("%s: %w", term.field, err) }
+      term.ranges = append(term.ranges, date_range)
+    }
+
+  case "duration":
+    if term.op == "=" || term.op == "~" { term.op = ":" }
+    for _, value := range term.values {
+      duration, err := time.ParseDuration(value)
+      if err != nil { return fmt.Errorf("duration: %w", err) }
+      term.durations = append(term.durations, duration)
+    }
+
+  default:
+    return fmt.Errorf("unknown field \"%s\", expected one of: %s", term.field, strings.Join(QUERY_FIELDS, ", "))
+  }
+
+  for _, value := range term.values {
+    if value == "" && term.field == "" {
+      return fmt.Errorf("empty word in query")
+    }
+    if value == "" {
+      return fmt.Errorf("empty value in term \"%s%s\"", term.field, term.op)
+    }
+  }
+
+  return nil
+}
+
+
+func ParseRecordQuery (source string) (query * RecordQuery, err error) {
+  query   = & RecordQuery { Source: source }
+  scanner := & queryScanner { input: [] rune(source) }
+
+  for {
+    scanner.skipSpace()
+    if scanner.done() { break }
+
+    term := queryTerm {}
+
+    if scanner.peek() == '-' {
+      term.negate = true
+      scanner.pos++
+      if scanner.done() || unicode.IsSpace(scanner.peek()) {
+        return nil, fmt.Errorf("\"-\" at position %d negates nothing; put it right before a term", scanner.pos)
+      }
+    }
+
+    term.field, term.op = scanner.scanFieldOp()
+
+    for {
+      value, err := scanner.scanValue()
+      if err != nil { return nil, err }
+      term.values = append(term.values, value)
+
+      if scanner.peek() != ',' { break }
+      scanner.pos++
+    }
+
+    if err = parseQueryTerm(&term); err != nil { return nil, err }
+    query.terms = append(query.terms, term)
+  }
+
+  return query, nil
+}
+
+
+func queryTextMatches (op, text, value string) bool {
+  if op == "~" {
+    return strings.Contains(strings.ToLower(text), strings.ToLower(value))
+  }
+  return strings.EqualFold(text, value)
+}
+
+
+func (term *queryTerm) matchValue (record * ActivityRecord, value_i int) bool {
+  value := term.values[value_i]
+
+  switch term.field {
+  case "":
+    return queryTextMatches("~", record.Activity_name, value) || queryTextMatches("~", record.Comment, value)
+
+  case "activity":
+    return queryTextMatches(term.op, record.Activity_name, value)
+
+  case "comment":
+    return queryTextMatches(term.op, record.Comment, value)
+
+  case "category":
+    for _, category := range record.Categories {
+      if queryTextMatches(term.op, category, value) { return true }
+    }
+    return false
+
+  case "tag":
+    for _, tag := range record.Tags() {
+      if queryTextMatches(term.op, tag, value) { return true }
+    }
+    return false
+
+  case "after":
+    first_day := term.ranges[value_i].FirstDay()
+    return first_day == nil || ! record.DayStart().Before(*first_day)
+
+  case "before":
+    last_day := term.ranges[value_i].LastDay()
+    return last_day == nil || ! record.DayStart().After(*last_day)
+
+  case "on":
+    return term.ranges[value_i].Contains(record.DayStart())
+
+  case "duration":
+    duration := term.durations[value_i]
+    switch term.op {
+    case "<":  return record.Duration <  duration
+    case "<=": return record.Duration <= duration
+    case ">":  return record.Duration >  duration
+    case ">=": return record.Duration >= duration
+    }
+    return record.Duration == duration
+  }
+
+  return false
+}
+
+
+func (term *queryTerm) match (record * ActivityRecord) bool {
+  for value_i := range term.values {
+    if term.matchValue(record, value_i) {
+      return ! term.negate
+    }
+  }
+  return term.negate
+}
+
+
+func (query *RecordQuery) Match (record * ActivityRecord) bool {
+  for term_i := range query.terms {
+    if ! query.terms[term_i].match(record) { return false }
+  }
+  return true
+}
+
+
+func (query *RecordQuery) Filter (records [] ActivityRecord) [] ActivityRecord {
+  filtered := make([] ActivityRecord, len(records))
+  count    := 0
+
+  for record_i := range records {
+    if query.Match(&records[record_i]) {
+      filtered[count] = records[record_i]
+      count++
+    }
+  }
+
+  return filtered[:count]
+}
@@ -0,0 +1,92 @@
+package stt_records;
+
+
+import (
+  "strings"
+  "testing"
+)
+
+
+func TestParseRecordQueryErrors (t * testing.T) {
+  tests := [] struct {
+    query string;
+    err   string;  // part of the error, or empty for none
+  } {
+    { "",                                 "" },
+    { "category:Development -activity:Email", "" },
+    { `comment~"a \"quoted\" word"`,      "" },
+    { "duration>=1h30m on:2024-03",       "" },
+    { "-",                                `"-" at position 1 negates nothing` },
+    { "email - meeting",                  `"-" at position 7 negates nothing` },
+    { "category:",                        `empty value in term "category:"` },
+    { "email,",                           "empty word" },
+    { `comment:"open`,                    "unterminated quote" },
+    { "colour:red",                       `unknown field "colour"` },
+    { "activity>Email",                   `activity takes ":" or "~"` },
+    { "after~2024",                       `after takes ":"` },
+    { "duration<an hour",                 "duration:" },
+    { "on:someday",                       "on:" },
+  }
+
+  for _, test := range tests {
+    _, err := ParseRecordQuery(test.query)
+    switch {
+    case test.err == "" && err != nil:
+      t.Errorf("%q: unexpected error: %v", test.query, err)
+    case test.err != "" && err == nil:
+      t.Errorf("%q: no error, want one containing %q", test.query, test.err)
+    case test.err != "" && ! strings.Contains(err.Error(), test.err):
+      t.Errorf("%q: error %q, want one containing %q", test.query, err, test.err)
+    }
+  }
+}
+
+
+func TestRecordQueryMatch (t * testing.T) {
+  email := testRecord("Email", "2024-03-04 09:00", "2024-03-04 09:20")
+  email.Categories  = [] string { "Productivity" }
+  email.Record_tags = "client=acme, quick"
+  email.Comment     = "Replies to the Acme thread"
+
+  coding := testRecord("Development", "2024-03-05 10:00", "2024-03-05 12:00")
+  coding.Categories = [] string { "Productivity", "Development" }
+
+  tests := [] struct {
+    query  string;
+    email  bool;
+    coding bool;
+  } {
+    { "",                                    true,  true  },
+    { "email",                               true,  false },
+    { "acme",                                true,  false },
+    { "activity:email",                      true,  false },
+    { "activity:emai",                       false, false },
+    { "activity~emai",                       true,  false },
+    { "-activity:Email",                     false, true  },
+    { "category:Development",                false, true  },
+    { "category:Development,Productivity",   true,  true  },
+    { "tag:client=acme",                     true,  false },
+    { "comment~thread",                      true,  false },
+    { `comment:"replies to the acme thread"`, true,  false },
+    { "duration>30m",                        false, true  },
+    { "duration<=20m",                       true,  false },
+    { "on:2024-03-04",                       true,  false },
+    { "after:2024-03-05",                    false, true  },
+    { "before:2024-03-04",                   true,  false },
+    { "on:2024-03 -duration:2h",             true,  false },
+  }
+
+  for _, test := range tests {
+    query, err := ParseRecordQuery(test.query)
+    if err != nil {
+      t.Errorf("%q: %v", test.query, err)
+      continue
+    }
+    if got := query.Match(&email); got != test.email {
+      t.Errorf("%q matching the email record: got %v, want %v", test.query, got, test.email)
+    }
+    if got := query.Match(&coding); got != test.coding {
+      t.Errorf("%q matching the coding record: got %v, want %v", test.query, got, test.coding)
+    }
+  }
+}
@@ -0,0 +1,1060 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "image"
+  "image/color"
+  "image/png"
+  "io"
+  "math"
+  "strconv"
+  "strings"
+
+  "golang.org/x/image/font"
+  "golang.org/x/image/font/gofont/gobold"
+  "golang.org/x/image/font/gofont/goregular"
+  "golang.org/x/image/font/opentype"
+  "golang.org/x/image/math/fixed"
+  "golang.org/x/image/vector"
+)
+
+
+//
+// Rasterizing charts to PNG, in pure Go. Charts are drawn from the SVG they
+// render, so every chart (and any new one) can be a PNG too. This only
+// understands the SVG the charts write: rects, circles, lines, polylines,
+// polygons and paths (M, L, H, V, C, Q, A and Z commands), text, linear
+// gradients, presentation attributes, and style rules by element and class
+// (ignoring :hover and other pseudo-classes). Text is set in the Go fonts,
+// which are compiled in.
+//
+
+
+// The largest PNG, in pixels, that charts will be rasterized to
+const RASTER_PIXELS_MAX int = 4096 * 4096
+
+// The DPI at which a chart's CSS pixels are the PNG's pixels
+const RASTER_DPI_DEFAULT float64 = 96
+
+var ErrRasterTooLarge error = errors.New("image too large")
+
+
+type rasterNode struct {
+  name       string;
+  attributes map [string] string;
+  children   [] * rasterNode;
+  text       string;
+}
+
+
+func rasterParseSvg (svg_source [] byte) (* rasterNode, error) {
+  decoder := xml.NewDecoder(bytes.NewReader(svg_source))
+
+  var root * rasterNode
+  var stack [] * rasterNode
+  for {
+    token, err := decoder.Token()
+    if errors.Is(err, io.EOF) { break }
+    if err != nil { return nil, err }
+
+    switch token := token.(type) {
+    case xml.StartElement:
+      node := & rasterNode { name: token.Name.Local, attributes: make(map [string] string, len(token.Attr)) }
+      for _, attribute := range token.Attr {
+        node.attributes[attribute.Name.Local] = attribute.Value
+      }
+      if len(stack) > 0 {
+        parent := stack[len(stack) - 1]
+        parent.children = append(parent.children, node)
+      } else {
+        root = node
+      }
+      stack = append(stack, node)
+
+    case xml.EndElement:
+      stack = stack[:len(stack) - 1]
+
+    case xml.CharData:
+      if len(stack) > 0 {
+        stack[len(stack) - 1].text += string(token)
+      }
+    }
+  }
+
+  if root == nil || root.name != "svg" {
+    return nil, fmt.Errorf("no svg element to rasterize")
+  }
+  return root, nil
+}
+
+
+//
+// Styles
+//
+
+
+type rasterStyleRule struct {
+  element      string;
+  class        string;
+  declarations map [string] string;
+}
+
+
+var raster_inherited_properties [] string = [] string {
+  "fill", "fill-opacity", "stroke", "stroke-opacity", "stroke-width",
+  "font-size", "font-weight", "text-anchor",
+}
+
+var raster_properties map [string] bool = map [string] bool {
+  "fill": true, "fill-opacity": true, "stroke": true, "stroke-opacity": true, "stroke-width": true,
+  "font-size": true, "font-weight": true, "text-anchor": true, "dominant-baseline": true,
+  "opacity": true, "display": true, "visibility": true,
+}
+
+
+func rasterParseDeclarations (declarations_str string, declarations map [string] string) {
+  for _, declaration := range strings.Split(declarations_str, ";") {
+    property, value, found := strings.Cut(declaration, ":")
+    if ! found { continue }
+    declarations[strings.TrimSpace(property)] = strings.TrimSpace(value)
+  }
+}
+
+
+func rasterParseStyleRules (style_str string) (rules [] rasterStyleRule) {
+  /*
+    Rules by element, class or both ("text", ".total", "text.total"), in
+    order. Rules with other selectors are skipped.
+  */
+
+  for _, block := range strings.Split(style_str, "}") {
+    selectors_str, declarations_str, found := strings.Cut(block, "{")
+    if ! found { continue }
+
+    declarations := make(map [string] string)
+    rasterParseDeclarations(declarations_str, declarations)
+
+    for _, selector := range strings.Split(selectors_str, ",") {
+      selector = strings.TrimSpace(selector)
+      if selector == "" || strings.ContainsAny(selector, ": >+~[#*") { continue }
+      element, class, _ := strings.Cut(selector, ".")
+      rules = append(rules, rasterStyleRule { element, class, declarations })
+    }
+  }
+  return rules
+}
+
+
+func (node * rasterNode) hasClass (class string) bool {
+  for _, node_class := range strings.Fields(node.attributes["class"]) {
+    if node_class == class {
+      return true
+    }
+  }
+  return false
+}
+
+
+func rasterComputeStyle (node * rasterNode, parent map [string] string, rules [] rasterStyleRule) map [string] string {
+  /*
+    A node's properties: those inherited from its parent, then its
+    presentation attributes, then the rules matching it, element rules before
+    rules with classes, then its style attribute.
+  */
+
+  style := make(map [string] string, len(raster_inherited_properties) + 4)
+  for _, property := range raster_inherited_properties {
+    if value, found := parent[property]; found {
+      style[property] = value
+    }
+  }
+
+  // Group opacity can't be composited here, so it's passed down, multiplied
+  style["opacity"] = parent["opacity"]
+
+  for property, value := range node.attributes {
+    if raster_properties[property] {
+      style[property] = value
+    }
+  }
+  if opacity, found := node.attributes["opacity"]; found {
+    style["opacity"] = strconv.FormatFloat(rasterParseNumber(parent["opacity"], 1) * rasterParseNumber(opacity, 1), 'g', -1, 64)
+  }
+
+  for _, with_class := range [] bool { false, true } {
+    for _, rule := range rules {
+      if (rule.class != "") != with_class { continue }
+      if rule.element != "" && rule.element != node.name { continue }
+      if rule.class != "" && ! node.hasClass(rule.class) { continue }
+      for property, value := range rule.declarations {
+        style[property] = value
+      }
+    }
+  }
+
+  if style_str, found := node.attributes["style"]; found {
+    rasterParseDeclarations(style_str, style)
+  }
+  return style
+}
+
+
+func rasterParseNumber (number_str string, fallback float64) float64 {
+  number_str = strings.TrimSuffix(strings.TrimSpace(number_str), "px")
+  number, err := strconv.ParseFloat(number_str, 64)
+  if err != nil {
+    return fallback
+  }
+  return number
+}
+
+
+var raster_named_colors map [string] color.NRGBA = map [string] color.NRGBA {
+  "black":       { 0, 0, 0, 255 },
+  "white":       { 255, 255, 255, 255 },
+  "gray":        { 128, 128, 128, 255 },
+  "grey":        { 128, 128, 128, 255 },
+  "red":         { 255, 0, 0, 255 },
+  "green":       { 0, 128, 0, 255 },
+  "blue":        { 0, 0, 255, 255 },
+  "transparent": { 0, 0, 0, 0 },
+}
+
+
+func rasterParseColor (color_str string) (color.NRGBA, bool) {
+  /*
+    A "#rgb", "#rgba", "#rrggbb", "#rrggbbaa" or named color, or false for
+    "none" or a color it can't read.
+  */
+
+  color_str = strings.ToLower(strings.TrimSpace(color_str))
+  if named, found := raster_named_colors[color_str]; found {
+    return named, true
+  }
+  if ! strings.HasPrefix(color_str, "#") {
+    return color.NRGBA {}, false
+  }
+
+  hex := color_str[1:]
+  if len(hex) == 3 || len(hex) == 4 {
+    long_hex := make([] byte, 0, 2 * len(hex))
+    for _, digit := range [] byte(hex) {
+      long_hex = append(long_hex, digit, digit)
+    }
+    hex = string(long_hex)
+  }
+  if len(hex) == 6 {
+    hex += "ff"
+  }
+  if len(hex) != 8 {
+    return color.NRGBA {}, false
+  }
+
+  value, err := strconv.ParseUint(hex, 16, 32)
+  if err != nil {
+    return color.NRGBA {}, false
+  }
+  return color.NRGBA { uint8(value >> 24), uint8(value >> 16), uint8(value >> 8), uint8(value) }, true
+}
+
+
+//
+// Geometry
+//
+
+
+type rasterPoint struct {
+  x, y float64;
+}
+
+
+type rasterSubpath struct {
+  points [] rasterPoint;
+  closed bool;
+}
+
+
+type rasterPathScanner struct {
+  data string;
+  pos  int;
+}
+
+
+func (scanner * rasterPathScanner) skipSeparators () {
+  for scanner.pos < len(scanner.data) && strings.IndexByte(" \t\r\n,", scanner.data[scanner.pos]) >= 0 {
+    scanner.pos++
+  }
+}
+
+
+func (scanner * rasterPathScanner) command () (byte, bool) {
+  scanner.skipSeparators()
+  if scanner.pos >= len(scanner.data) { return 0, false }
+  next := scanner.data[scanner.pos]
+  if (next >= 'a' && next <= 'z') || (next >= 'A' && next <= 'Z') {
+    scanner.pos++
+    return next, true
+  }
+  return 0, false
+}
+
+
+func (scanner * rasterPathScanner) number () (float64, error) {
+  scanner.skipSeparators()
+  start := scanner.pos
+  for scanner.pos < len(scanner.data) {
+    next := scanner.data[scanner.pos]
+    is_sign := (next == '-' || next == '+') &&
+      (scanner.pos == start || scanner.data[scanner.pos - 1] == 'e' || scanner.data[scanner.pos - 1] == 'E')
+    if ! (is_sign || (next >= '0' && next <= '9') || next == '.' || next == 'e' || next == 'E') { break }
+    scanner.pos++
+  }
+  number, err := strconv.ParseFloat(scanner.data[start:scanner.pos], 64)
+  if err != nil {
+    return 0, fmt.Errorf("invalid path number at %d", start)
+  }
+  return number, nil
+}
+
+
+func (scanner * rasterPathScanner) numbers (count int) ([] float64, error) {
+  numbers := make([] float64, count)
+  for number_i := range numbers {
+    number, err := scanner.number()
+    if err != nil { return nil, err }
+    numbers[number_i] = number
+  }
+  return numbers, nil
+}
+
+
+func rasterArcPoints (from rasterPoint, rx, ry, rotation_deg float64, large_arc, sweep bool, to rasterPoint) [] rasterPoint {
+  /*
+    Points along an elliptical arc, after its start, per the SVG
+    specification's conversion from endpoint to center parameterization.
+  */
+
+  rx, ry = math.Abs(rx), math.Abs(ry)
+  if rx == 0 || ry == 0 || (from == to) {
+    return [] rasterPoint { to }
+  }
+
+  phi := rotation_deg * math.Pi / 180
+  cos_phi, sin_phi := math.Cos(phi), math.Sin(phi)
+
+  dx, dy := (from.x - to.x) / 2, (from.y - to.y) / 2
+  x1 :=  cos_phi * dx + sin_phi * dy
+  y1 := -sin_phi * dx + cos_phi * dy
+
+  // Radii too small to reach are scaled up until they do
+  lambda := (x1 * x1) / (rx * rx) + (y1 * y1) / (ry * ry)
+  if lambda > 1 {
+    rx *= math.Sqrt(lambda)
+    ry *= math.Sqrt(lambda)
+  }
+
+  numerator   := rx * rx * ry * ry - rx * rx * y1 * y1 - ry * ry * x1 * x1
+  denominator := rx * rx * y1 * y1 + ry * ry * x1 * x1
+  coefficient := math.Sqrt(math.Max(0, numerator / denominator))
+  if large_arc == sweep {
+    coefficient = -coefficient
+  }
+  center_x1 :=  coefficient * rx * y1 / ry
+  center_y1 := -coefficient * ry * x1 / rx
+  center_x  := cos_phi * center_x1 - sin_phi * center_y1 + (from.x + to.x) / 2
+  center_y  := sin_phi * center_x1 + cos_phi * center_y1 + (from.y + to.y) / 2
+
+  start_t := math.Atan2((y1 - center_y1) / ry, (x1 - center_x1) / rx)
+  end_t   := math.Atan2((-y1 - center_y1) / ry, (-x1 - center_x1) / rx)
+  delta_t := end_t - start_t
+  if sweep && delta_t < 0 {
+    delta_t += 2 * math.Pi
+  } else if ! sweep && delta_t > 0 {
+    delta_t -= 2 * math.Pi
+  }
+
+  steps  := int(math.Max(4, math.Ceil(math.Abs(delta_t) / (math.Pi / 32))))
+  points := make([] rasterPoint, 0, steps)
+  for step := 1; step <= steps; step++ {
+    t := start_t + delta_t * float64(step) / float64(steps)
+    x, y := rx * math.Cos(t), ry * math.Sin(t)
+    points = append(points, rasterPoint { cos_phi * x - sin_phi * y + center_x, sin_phi * x + cos_phi * y + center_y })
+  }
+  points[len(points) - 1] = to
+  return points
+}
+
+
+func rasterCurvePoints (from, control_1, control_2, to rasterPoint) [] rasterPoint {
+  /*
+    Points along a cubic Bézier curve, after its start.
+  */
+  const steps = 16
+  points := make([] rasterPoint, 0, steps)
+  for step := 1; step <= steps; step++ {
+    t := float64(step) / steps
+    u := 1 - t
+    points = append(points, rasterPoint {
+      u*u*u * from.x + 3*u*u*t * control_1.x + 3*u*t*t * control_2.x + t*t*t * to.x,
+      u*u*u * from.y + 3*u*u*t * control_1.y + 3*u*t*t * control_2.y + t*t*t * to.y,
+    })
+  }
+  return points
+}
+
+
+func rasterParsePath (data string) ([] rasterSubpath, error) {
+  scanner := rasterPathScanner { data: data }
+
+  var subpaths [] rasterSubpath
+  var current, start rasterPoint
+  var command byte
+
+  lineTo := func (points ...rasterPoint) {
+    if len(subpaths) == 0 || subpaths[len(subpaths) - 1].closed {
+      subpaths = append(subpaths, rasterSubpath { points: [] rasterPoint { current } })
+    }
+    subpath := &subpaths[len(subpaths) - 1]
+    subpath.points = append(subpath.points, points...)
+    current = points[len(points) - 1]
+  }
+
+  for {
+    scanner.skipSeparators()
+    if scanner.pos >= len(scanner.data) { break }
+
+    // Without a command letter, the previous command repeats
+    if next_command, found := scanner.command(); found {
+      command = next_command
+    } else if command == 0 {
+      return nil, fmt.Errorf("invalid path data at %d", scanner.pos)
+    }
+
+    relative := command >= 'a'
+    origin   := rasterPoint {}
+    if relative {
+      origin = current
+    }
+
+    switch command {
+    case 'Z', 'z':
+      if len(subpaths) > 0 {
+        subpaths[len(subpaths) - 1].closed = true
+      }
+      current = start
+      command = 0
+
+    case 'M', 'm':
+      numbers, err := scanner.numbers(2)
+      if err != nil { return nil, err }
+      current  = rasterPoint { origin.x + numbers[0], origin.y + numbers[1] }
+      start    = current
+      subpaths = append(subpaths, rasterSubpath { points: [] rasterPoint { current } })
+
+      // Coordinates after a move are lines
+      command = 'L'
+      if relative {
+        command = 'l'
+      }
+
+    case 'L', 'l':
+      numbers, err := scanner.numbers(2)
+      if err != nil { return nil, err }
+      lineTo(rasterPoint { origin.x + numbers[0], origin.y + numbers[1] })
+
+    case 'H', 'h':
+      numbers, err := scanner.numbers(1)
+      if err != nil { return nil, err }
+      lineTo(rasterPoint { origin.x + numbers[0], current.y })
+
+    case 'V', 'v':
+      numbers, err := scanner.numbers(1)
+      if err != nil { return nil, err }
+      lineTo(rasterPoint { current.x, origin.y + numbers[0] })
+
+    case 'C', 'c':
+      numbers, err := scanner.numbers(6)
+      if err != nil { return nil, err }
+      lineTo(rasterCurvePoints(
+        current,
+        rasterPoint { origin.x + numbers[0], origin.y + numbers[1] },
+        rasterPoint { origin.x + numbers[2], origin.y + numbers[3] },
+        rasterPoint { origin.x + numbers[4], origin.y + numbers[5] },
+      )...)
+
+    case 'Q', 'q':
+      numbers, err := scanner.numbers(4)
+      if err != nil { return nil, err }
+      control := rasterPoint { origin.x + numbers[0], origin.y + numbers[1] }
+      to      := rasterPoint { origin.x + numbers[2], origin.y + numbers[3] }
+      lineTo(rasterCurvePoints(
+        current,
+        rasterPoint { current.x + 2.0 / 3 * (control.x - current.x), current.y + 2.0 / 3 * (control.y - current.y) },
+        rasterPoint { to.x + 2.0 / 3 * (control.x - to.x), to.y + 2.0 / 3 * (control.y - to.y) },
+        to,
+      )...)
+
+    case 'A', 'a':
+      numbers, err := scanner.numbers(7)
+      if err != nil { return nil, err }
+      lineTo(rasterArcPoints(
+        current, numbers[0], numbers[1], numbers[2], numbers[3] != 0, numbers[4] != 0,
+        rasterPoint { origin.x + numbers[5], origin.y + numbers[6] },
+      )...)
+
+    default:
+      return nil, fmt.Errorf("unsupported path command \"%c\"", command)
+    }
+  }
+
+  return subpaths, nil
+}
+
+
+func rasterParsePoints (points_str string) [] rasterPoint {
+  numbers := strings.FieldsFunc(points_str, func (r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\t' })
+  points  := make([] rasterPoint, 0, len(numbers) / 2)
+  for number_i := 0; number_i + 1 < len(numbers); number_i += 2 {
+    points = append(points, rasterPoint { rasterParseNumber(numbers[number_i], 0), rasterParseNumber(numbers[number_i + 1], 0) })
+  }
+  return points
+}
+
+
+func rasterShape (node * rasterNode) ([] rasterSubpath, error) {
+  /*
+    The outline of a shape element, in user units.
+  */
+
+  number := func (name string) float64 {
+    return rasterParseNumber(node.attributes[name], 0)
+  }
+
+  switch node.name {
+  case "rect":
+    x, y, width, height := number("x"), number("y"), number("width"), number("height")
+    if width <= 0 || height <= 0 { return nil, nil }
+    return [] rasterSubpath { {
+      points: [] rasterPoint { { x, y }, { x + width, y }, { x + width, y + height }, { x, y + height } },
+      closed: true,
+    } }, nil
+
+  case "circle", "ellipse":
+    rx, ry := number("r"), number("r")
+    if node.name == "ellipse" {
+      rx, ry = number("rx"), number("ry")
+    }
+    if rx <= 0 || ry <= 0 { return nil, nil }
+    const steps = 64
+    circle := rasterSubpath { closed: true }
+    for step := 0; step < steps; step++ {
+      t := 2 * math.Pi * float64(step) / steps
+      circle.points = append(circle.points, rasterPoint { number("cx") + rx * math.Cos(t), number("cy") + ry * math.Sin(t) })
+    }
+    return [] rasterSubpath { circle }, nil
+
+  case "line":
+    return [] rasterSubpath { {
+      points: [] rasterPoint { { number("x1"), number("y1") }, { number("x2"), number("y2") } },
+    } }, nil
+
+  case "polyline", "polygon":
+    return [] rasterSubpath { {
+      points: rasterParsePoints(node.attributes["points"]),
+      closed: node.name == "polygon",
+    } }, nil
+
+  case "path":
+    return rasterParsePath(node.attributes["d"])
+  }
+  return nil, nil
+}
+
+
+//
+// Drawing
+//
+
+
+type rasterGradientStop struct {
+  offset float64;
+  color  color.NRGBA;
+}
+
+
+type rasterGradient struct {
+  /*
+    A linear gradient, from (x1, y1) to (x2, y2) in device pixels.
+  */
+  x1, y1, x2, y2 float64;
+  stops          [] rasterGradientStop;
+}
+
+
+func (gradient * rasterGradient) ColorModel () color.Model { return color.NRGBAModel }
+
+func (gradient * rasterGradient) Bounds () image.Rectangle {
+  return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (gradient * rasterGradient) At (x, y int) color.Color {
+  if len(gradient.stops) == 0 { return color.NRGBA {} }
+
+  dx, dy := gradient.x2 - gradient.x1, gradient.y2 - gradient.y1
+  t := 0.0
+  if length := dx * dx + dy * dy; length > 0 {
+    t = ((float64(x) + 0.5 - gradient.x1) * dx + (float64(y) + 0.5 - gradient.y1) * dy) / length
+  }
+
+  if t <= gradient.stops[0].offset {
+    return gradient.stops[0].color
+  }
+  for stop_i := 1; stop_i < len(gradient.stops); stop_i++ {
+    before, after := gradient.stops[stop_i - 1], gradient.stops[stop_i]
+    if t > after.offset { continue }
+    mix := 0.0
+    if after.offset > before.offset {
+      mix = (t - before.offset) / (after.offset - before.offset)
+    }
+    channel := func (from, to uint8) uint8 {
+      return uint8(math.Round(float64(from) + mix * (float64(to) - float64(from))))
+    }
+    return color.NRGBA {
+      channel(before.color.R, after.color.R), channel(before.color.G, after.color.G),
+      channel(before.color.B, after.color.B), channel(before.color.A, after.color.A),
+    }
+  }
+  return gradient.stops[len(gradient.stops) - 1].color
+}
+
+
+type rasterFaceKey struct {
+  bold bool;
+  size float64;
+}
+
+
+type rasterCanvas struct {
+  image      * image.RGBA;
+  rasterizer * vector.Rasterizer;
+
+  // User units to device pixels
+  scale          float64;
+  offset_x       float64;
+  offset_y       float64;
+
+  rules          [] rasterStyleRule;
+  gradients      map [string] * rasterNode;
+  faces          map [rasterFaceKey] font.Face;
+}
+
+
+func (canvas * rasterCanvas) device (point rasterPoint) rasterPoint {
+  return rasterPoint { point.x * canvas.scale + canvas.offset_x, point.y * canvas.scale + canvas.offset_y }
+}
+
+
+func (canvas * rasterCanvas) paint (style map [string] string, property string, subpaths [] rasterSubpath) image.Image {
+  /*
+    The image to fill or stroke with, from the style's fill or stroke and
+    their opacities, or nil if there's nothing to draw.
+  */
+
+  value   := strings.TrimSpace(style[property])
+  opacity := rasterParseNumber(style["opacity"], 1) * rasterParseNumber(style[property + "-opacity"], 1)
+
+  if strings.HasPrefix(value, "url(#") {
+    gradient_node, found := canvas.gradients[strings.TrimSuffix(strings.TrimPrefix(value, "url(#"), ")")]
+    if ! found { return nil }
+    return canvas.gradient(gradient_node, subpaths, opacity)
+  }
+
+  paint_color, ok := rasterParseColor(value)
+  if ! ok { return nil }
+  paint_color.A = uint8(math.Round(float64(paint_color.A) * math.Max(0, math.Min(1, opacity))))
+  if paint_color.A == 0 { return nil }
+  return image.NewUniform(paint_color)
+}
+
+
+func (canvas * rasterCanvas) gradient (gradient_node * rasterNode, subpaths [] rasterSubpath, opacity float64) image.Image {
+  /*
+    A linear gradient across the bounding box of the subpaths it fills, as
+    with the default gradientUnits of objectBoundingBox.
+  */
+
+  min_x, min_y, max_x, max_y := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+  for _, subpath := range subpaths {
+    for _, point := range subpath.points {
+      device := canvas.device(point)
+      min_x, min_y = math.Min(min_x, device.x), math.Min(min_y, device.y)
+      max_x, max_y = math.Max(max_x, device.x), math.Max(max_y, device.y)
+    }
+  }
+
+  fraction := func (name string, fallback float64) float64 {
+    value := strings.TrimSpace(gradient_node.attributes[name])
+    if strings.HasSuffix(value, "%") {
+      return rasterParseNumber(strings.TrimSuffix(value, "%"), fallback * 100) / 100
+    }
+    return rasterParseNumber(value, fallback)
+  }
+
+  gradient := & rasterGradient {
+    x1: min_x + fraction("x1", 0) * (max_x - min_x),
+    y1: min_y + fraction("y1", 0) * (max_y - min_y),
+    x2: min_x + fraction("x2", 1) * (max_x - min_x),
+    y2: min_y + fraction("y2", 0) * (max_y - min_y),
+  }
+  for _, stop_node := range gradient_node.children {
+    if stop_node.name != "stop" { continue }
+    style := make(map [string] string)
+    rasterParseDeclarations(stop_node.attributes["style"], style)
+    for _, property := range [] string { "stop-color", "stop-opacity" } {
+      if value, found := stop_node.attributes[property]; found {
+        style[property] = value
+      }
+    }
+
+    stop_color, ok := rasterParseColor(style["stop-color"])
+    if ! ok {
+      stop_color = color.NRGBA { 0, 0, 0, 0 }
+    }
+    stop_opacity := rasterParseNumber(style["stop-opacity"], 1) * opacity
+    stop_color.A = uint8(math.Round(float64(stop_color.A) * math.Max(0, math.Min(1, stop_opacity))))
+
+    offset_str := strings.TrimSpace(stop_node.attributes["offset"])
+    offset := rasterParseNumber(strings.TrimSuffix(offset_str, "%"), 0)
+    if strings.HasSuffix(offset_str, "%") {
+      offset /= 100
+    }
+    gradient.stops = append(gradient.stops, rasterGradientStop { offset, stop_color })
+  }
+  return gradient
+}
+
+
+func (canvas * rasterCanvas) fillPolygons (polygons [][] rasterPoint, src image.Image) {
+  bounds := canvas.image.Bounds()
+  canvas.rasterizer.Reset(bounds.Dx(), bounds.Dy())
+  for _, polygon := range polygons {
+    if len(polygon) < 3 { continue }
+    canvas.rasterizer.MoveTo(float32(polygon[0].x), float32(polygon[0].y))
+    for _, point := range polygon[1:] {
+      canvas.rasterizer.LineTo(float32(point.x), float32(point.y))
+    }
+    canvas.rasterizer.ClosePath()
+  }
+  canvas.rasterizer.Draw(canvas.image, bounds, src, image.Point {})
+}
+
+
+func rasterCounterClockwise (polygon [] rasterPoint) [] rasterPoint {
+  /*
+    The polygon wound one way, so that overlapping polygons in one fill don't
+    cancel each other out.
+  */
+  area := 0.0
+  for point_i, point := range polygon {
+    next := polygon[(point_i + 1) % len(polygon)]
+    area += point.x * next.y - next.x * point.y
+  }
+  if area < 0 {
+    reversed := make([] rasterPoint, len(polygon))
+    for point_i, point := range polygon {
+      reversed[len(polygon) - 1 - point_i] = point
+    }
+    return reversed
+  }
+  return polygon
+}
+
+
+func rasterStrokePolygons (subpaths [] rasterSubpath, width float64) (polygons [][] rasterPoint) {
+  /*
+    A stroke's outline, as a quadrilateral per segment and a disc at each
+    vertex, for round joins and caps.
+  */
+
+  half := width / 2
+  disc := func (center rasterPoint) [] rasterPoint {
+    steps  := int(math.Max(8, math.Min(32, math.Ceil(half * 4))))
+    points := make([] rasterPoint, steps)
+    for step := range points {
+      t := 2 * math.Pi * float64(step) / float64(steps)
+      points[step] = rasterPoint { center.x + half * math.Cos(t), center.y + half * math.Sin(t) }
+    }
+    return points
+  }
+
+  for _, subpath := range subpaths {
+    points := subpath.points
+    if subpath.closed && len(points) > 1 {
+      points = append(append([] rasterPoint {}, points...), points[0])
+    }
+    for point_i := 0; point_i + 1 < len(points); point_i++ {
+      from, to := points[point_i], points[point_i + 1]
+      length := math.Hypot(to.x - from.x, to.y - from.y)
+      if length == 0 { continue }
+      normal_x, normal_y := -(to.y - from.y) / length * half, (to.x - from.x) / length * half
+      polygons = append(polygons, rasterCounterClockwise([] rasterPoint {
+        { from.x + normal_x, from.y + normal_y }, { to.x + normal_x, to.y + normal_y },
+        { to.x - normal_x, to.y - normal_y }, { from.x - normal_x, from.y - normal_y },
+      }))
+    }
+    if half > 0.75 {
+      for _, point := range points {
+        polygons = append(polygons, rasterCounterClockwise(disc(point)))
+      }
+    }
+  }
+  return polygons
+}
+
+
+func (canvas * rasterCanvas) face (style map [string] string) font.Face {
+  key := rasterFaceKey {
+    bold: style["font-weight"] == "bold" || rasterParseNumber(style["font-weight"], 400) >= 600,
+    size: math.Max(1, rasterParseNumber(style["font-size"], 16) * canvas.scale),
+  }
+  if face, found := canvas.faces[key]; found {
+    return face
+  }
+
+  ttf := goregular.TTF
+  if key.bold {
+    ttf = gobold.TTF
+  }
+  parsed, err := opentype.Parse(ttf)
+  if err != nil { return nil }
+  face, err := opentype.NewFace(parsed, & opentype.FaceOptions { Size: key.size, DPI: 72, Hinting: font.HintingNone })
+  if err != nil { return nil }
+  canvas.faces[key] = face
+  return face
+}
+
+
+func (canvas * rasterCanvas) drawText (node * rasterNode, style map [string] string) {
+  var text_builder strings.Builder
+  text_builder.WriteString(node.text)
+  for _, child := range node.children {
+    if child.name == "tspan" {
+      text_builder.WriteString(child.text)
+    }
+  }
+  text := strings.Join(strings.Fields(text_builder.String()), " ")
+  if text == "" { return }
+
+  if style["fill"] == "" {
+    style["fill"] = "black"
+  }
+  src := canvas.paint(style, "fill", nil)
+  if src == nil { return }
+  face := canvas.face(style)
+  if face == nil { return }
+
+  position := canvas.device(rasterPoint { rasterParseNumber(node.attributes["x"], 0), rasterParseNumber(node.attributes["y"], 0) })
+  advance  := float64(font.MeasureString(face, text)) / 64
+  switch style["text-anchor"] {
+  case "middle":
+    position.x -= advance / 2
+  case "end":
+    position.x -= advance
+  }
+  switch style["dominant-baseline"] {
+  case "middle", "central":
+    position.y += float64(face.Metrics().XHeight) / 64 / 2
+  case "hanging", "text-before-edge":
+    position.y += float64(face.Metrics().Ascent) / 64
+  }
+
+  drawer := font.Drawer {
+    Dst:  canvas.image,
+    Src:  src,
+    Face: face,
+    Dot:  fixed.Point26_6 { X: fixed.Int26_6(math.Round(position.x * 64)), Y: fixed.Int26_6(math.Round(position.y * 64)) },
+  }
+  drawer.DrawString(text)
+}
+
+
+func (canvas * rasterCanvas) draw (node * rasterNode, parent_style map [string] string) error {
+  style := rasterComputeStyle(node, parent_style, canvas.rules)
+  if style["display"] == "none" || style["visibility"] == "hidden" {
+    return nil
+  }
+
+  switch node.name {
+  case "title", "desc", "style", "defs", "metadata", "linearGradient", "stop":
+    return nil
+
+  case "svg", "g", "a":
+    for _, child := range node.children {
+      if err := canvas.draw(child, style); err != nil { return err }
+    }
+    return nil
+
+  case "text":
+    canvas.drawText(node, style)
+    return nil
+  }
+
+  subpaths, err := rasterShape(node)
+  if err != nil { return fmt.Errorf("%s: %w", node.name, err) }
+  if len(subpaths) == 0 { return nil }
+
+  device_subpaths := make([] rasterSubpath, len(subpaths))
+  for subpath_i, subpath := range subpaths {
+    device_subpaths[subpath_i].closed = subpath.closed
+    for _, point := range subpath.points {
+      device_subpaths[subpath_i].points = append(device_subpaths[subpath_i].points, canvas.device(point))
+    }
+  }
+
+  // Lines aren't filled; other shapes are, black by default
+  if node.name != "line" {
+    if style["fill"] == "" {
+      style["fill"] = "black"
+    }
+    if src := canvas.paint(style, "fill", subpaths); src != nil {
+      polygons := make([][] rasterPoint, len(device_subpaths))
+      for subpath_i, subpath := range device_subpaths {
+        polygons[subpath_i] = subpath.points
+      }
+      canvas.fillPolygons(polygons, src)
+    }
+  }
+
+  if src := canvas.paint(style, "stroke", subpaths); src != nil {
+    width := rasterParseNumber(style["stroke-width"], 1) * canvas.scale
+    if width > 0 {
+      canvas.fillPolygons(rasterStrokePolygons(device_subpaths, width), src)
+    }
+  }
+  return nil
+}
+
+
+func rasterCollect (node * rasterNode, rules * [] rasterStyleRule, gradients map [string] * rasterNode) {
+  switch node.name {
+  case "style":
+    *rules = append(*rules, rasterParseStyleRules(node.text)...)
+  case "linearGradient":
+    if id := node.attributes["id"]; id != "" {
+      gradients[id] = node
+    }
+  }
+  for _, child := range node.children {
+    rasterCollect(child, rules, gradients)
+  }
+}
+
+
+func SvgRasterize (svg_source [] byte, dpi float64, background color.Color) (* image.RGBA, error) {
+  /*
+    Draw an SVG document (of the kind the charts write) as an image, at its
+    width and height in CSS pixels at the given DPI, its contents fitted to
+    that by its viewBox. Without a width or height, the image follows the
+    viewBox's aspect ratio, or size.
+  */
+
+  root, err := rasterParseSvg(svg_source)
+  if err != nil { return nil, err }
+
+  view_box := [] float64 { 0, 0, 0, 0 }
+  if view_box_fields := strings.Fields(strings.ReplaceAll(root.attributes["viewBox"], ",", " ")); len(view_box_fields) == 4 {
+    for field_i, field := range view_box_fields {
+      view_box[field_i] = rasterParseNumber(field, 0)
+    }
+  }
+  width  := rasterParseNumber(root.attributes["width"],  0)
+  height := rasterParseNumber(root.attributes["height"], 0)
+
+  switch {
+  case view_box[2] <= 0 || view_box[3] <= 0:
+    if width <= 0 || height <= 0 {
+      return nil, fmt.Errorf("svg has no size to rasterize at")
+    }
+    view_box = [] float64 { 0, 0, width, height }
+  case width <= 0 && height <= 0:
+    width, height = view_box[2], view_box[3]
+  case height <= 0:
+    height = width * view_box[3] / view_box[2]
+  case width <= 0:
+    width = height * view_box[2] / view_box[3]
+  }
+
+  pixel_scale  := dpi / RASTER_DPI_DEFAULT
+  pixel_width  := int(math.Round(width  * pixel_scale))
+  pixel_height := int(math.Round(height * pixel_scale))
+  if pixel_width < 1 || pixel_height < 1 || pixel_width * pixel_height > RASTER_PIXELS_MAX {
+    return nil, fmt.Errorf("%w: %dx%d pixels, the most is %d pixels", ErrRasterTooLarge, pixel_width, pixel_height, RASTER_PIXELS_MAX)
+  }
+
+  // The viewBox is fitted in the middle, keeping its aspect ratio
+  scale := math.Min(float64(pixel_width) / view_box[2], float64(pixel_height) / view_box[3])
+  canvas := rasterCanvas {
+    image:      image.NewRGBA(image.Rect(0, 0, pixel_width, pixel_height)),
+    rasterizer: vector.NewRasterizer(pixel_width, pixel_height),
+    scale:      scale,
+    offset_x:   (float64(pixel_width)  - view_box[2] * scale) / 2 - view_box[0] * scale,
+    offset_y:   (float64(pixel_height) - view_box[3] * scale) / 2 - view_box[1] * scale,
+    gradients:  make(map [string] * rasterNode),
+    faces:      make(map [rasterFaceKey] font.Face),
+  }
+  rasterCollect(root, &canvas.rules, canvas.gradients)
+
+  if background != nil {
+    canvas.fillPolygons(
+      [][] rasterPoint { { { 0, 0 }, { float64(pixel_width), 0 }, { float64(pixel_width), float64(pixel_height) }, { 0, float64(pixel_height) } } },
+      image.NewUniform(background),
+    )
+  }
+
+  if err := canvas.draw(root, map [string] string { "opacity": "1" }); err != nil {
+    return nil, err
+  }
+  return canvas.image, nil
+}
+
+
+func ChartBackground (theme string) color.Color {
+  /*
+    The background a chart's PNG has for its theme: the charts' SVG is
+    transparent, but not everywhere PNGs are shown has a background to match.
+  */
+  if theme == "dark" {
+    return color.NRGBA { 0x22, 0x22, 0x22, 0xff }
+  }
+  return color.White
+}
+
+
+func ChartRenderPNG (
+  writer    io.Writer,
+  chart     Chart,
+  records [] ActivityRecord,
+  options  * ActivityRecordChartOptions,
+  dpi       float64,
+) error {
+  /*
+    Render a chart as a PNG, at the given DPI (RASTER_DPI_DEFAULT for the
+    chart's size in pixels), on the background for its theme.
+  */
+
+  var svg bytes.Buffer
+  if err := chart.Render(&svg, records, options); err != nil { return err }
+
+  theme := ""
+  if options != nil {
+    theme = options.Theme
+  }
+  chart_image, err := SvgRasterize(svg.Bytes(), dpi, ChartBackground(theme))
+  if err != nil { return err }
+
+  return png.Encode(writer, chart_image)
+}
@@ -0,0 +1,325 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "errors"
+  "image/color"
+  "image/png"
+  "math"
+  "testing"
+)
+
+
+func TestRasterParseColor (t * testing.T) {
+  tests := [] struct {
+    color_str string;
+    want      color.NRGBA;
+    ok        bool;
+  } {
+    { "#f00",       color.NRGBA { 255, 0, 0, 255 },     true },
+    { "#f008",      color.NRGBA { 255, 0, 0, 136 },     true },
+    { "#2a7ab0",    color.NRGBA { 42, 122, 176, 255 },  true },
+    { "#2A7AB080",  color.NRGBA { 42, 122, 176, 128 },  true },
+    { " White ",    color.NRGBA { 255, 255, 255, 255 }, true },
+    { "transparent", color.NRGBA {},                    true },
+    { "none",       color.NRGBA {},                     false },
+    { "#12345",     color.NRGBA {},                     false },
+    { "#ggg",       color.NRGBA {},                     false },
+    { "rgb(1,2,3)", color.NRGBA {},                     false },
+    { "",           color.NRGBA {},                     false },
+  }
+
+  for _, test := range tests {
+    got, ok := rasterParseColor(test.color_str)
+    if ok != test.ok || got != test.want {
+      t.Errorf("%q: got %v, %v, want %v, %v", test.color_str, got, ok, test.want, test.ok)
+    }
+  }
+}
+
+
+func TestRasterParsePath (t * testing.T) {
+  // Each subpath's closedness, and its first and final points
+  type subpath struct {
+    closed       bool;
+    first, final rasterPoint;
+  }
+
+  tests := [] struct {
+    data string;
+    want [] subpath;
+    err  bool;
+  } {
+    { "M 1 2 L 3 4",                 [] subpath { { false, rasterPoint { 1, 2 }, rasterPoint { 3, 4 } } }, false },
+    { "M1,2L3,4Z",                   [] subpath { { true,  rasterPoint { 1, 2 }, rasterPoint { 3, 4 } } }, false },
+    { "M 1 2 3 4 5 6",               [] subpath { { false, rasterPoint { 1, 2 }, rasterPoint { 5, 6 } } }, false },
+    { "m 1 2 l 3 4 h 1 v -1",        [] subpath { { false, rasterPoint { 1, 2 }, rasterPoint { 5, 5 } } }, false },
+    { "M 0 0 H 10 V 10 H 0 Z",       [] subpath { { true,  rasterPoint { 0, 0 }, rasterPoint { 0, 10 } } }, false },
+    { "M 0 0 C 1 1 2 1 3 0",         [] subpath { { false, rasterPoint { 0, 0 }, rasterPoint { 3, 0 } } }, false },
+    { "M 0 0 Q 1 1 2 0",             [] subpath { { false, rasterPoint { 0, 0 }, rasterPoint { 2, 0 } } }, false },
+    { "M 1 0 A 1 1 0 0 1 -1 0",      [] subpath { { false, rasterPoint { 1, 0 }, rasterPoint { -1, 0 } } }, false },
+    { "M 1e1 -2.5e-1 L .5 -.5",      [] subpath { { false, rasterPoint { 10, -0.25 }, rasterPoint { 0.5, -0.5 } } }, false },
+    {
+      "M 1 0 A 1 1 0 0 1 -1 0 A 1 1 0 0 1 1 0 Z M 0.5 0 A 0.5 0.5 0 0 0 -0.5 0 A 0.5 0.5 0 0 0 0.5 0 Z",
+      [] subpath { { true, rasterPoint { 1, 0 }, rasterPoint { 1, 0 } }, { true, rasterPoint { 0.5, 0 }, rasterPoint { 0.5, 0 } } },
+      false,
+    },
+    { "",            nil, false },
+    { "1 2",         nil, true },
+    { "M 1",         nil, true },
+    { "M 1 2 L x 4", nil, true },
+    { "M 0 0 S 1 1", nil, true },
+  }
+
+  near := func (a, b rasterPoint) bool {
+    return math.Abs(a.x - b.x) < 1e-9 && math.Abs(a.y - b.y) < 1e-9
+  }
+
+  for _, test := range tests {
+    got, err := rasterParsePath(test.data)
+    if (err != nil) != test.err {
+      t.Errorf("%q: error %v", test.data, err)
+      continue
+    }
+    if len(got) != len(test.want) {
+      t.Errorf("%q: %d subpaths, want %d", test.data, len(got), len(test.want))
+      continue
+    }
+    for subpath_i, want := range test.want {
+      points := got[subpath_i].points
+      if got[subpath_i].closed != want.closed || ! near(points[0], want.first) || ! near(points[len(points) - 1], want.final) {
+        t.Errorf(
+          "%q: subpath %d is %v, %v..%v, want %v, %v..%v",
+          test.data, subpath_i, got[subpath_i].closed, points[0], points[len(points) - 1], want.closed, want.first, want.final,
+        )
+      }
+    }
+  }
+}
+
+
+func TestRasterArcPoints (t * testing.T) {
+  // Arcs' points are on their ellipse, and on the side the flags choose
+  tests := [] struct {
+    large_arc, sweep bool;
+    mid_y            float64;
+  } {
+    // From (1, 0) to (-1, 0) on the unit circle, y down: a clockwise sweep
+    // goes through (0, 1), and anticlockwise through (0, -1)
+    { false, true,  1 },
+    { false, false, -1 },
+    { true,  true,  1 },
+    { true,  false, -1 },
+  }
+
+  for _, test := range tests {
+    points := rasterArcPoints(rasterPoint { 1, 0 }, 1, 1, 0, test.large_arc, test.sweep, rasterPoint { -1, 0 })
+    mid_y  := 0.0
+    for _, point := range points {
+      if radius := math.Hypot(point.x, point.y); math.Abs(radius - 1) > 1e-9 {
+        t.Errorf("%v, %v: %v is %v from the center", test.large_arc, test.sweep, point, radius)
+      }
+      if math.Abs(point.y) > math.Abs(mid_y) {
+        mid_y = point.y
+      }
+    }
+    if math.Abs(mid_y - test.mid_y) > 0.01 {
+      t.Errorf("%v, %v: goes through y %v, want %v", test.large_arc, test.sweep, mid_y, test.mid_y)
+    }
+  }
+
+  // A degenerate arc is a line to its end
+  points := rasterArcPoints(rasterPoint { 0, 0 }, 0, 1, 0, false, true, rasterPoint { 2, 0 })
+  if final := points[len(points) - 1]; final != (rasterPoint { 2, 0 }) {
+    t.Errorf("zero radius: ends at %v", final)
+  }
+}
+
+
+func TestRasterComputeStyle (t * testing.T) {
+  rules := rasterParseStyleRules(
+    "rect { fill: red; stroke: blue; }\n" +
+    ".total { fill: green; }\n" +
+    "rect.cell:hover, rect > g { fill: black; }\n" +
+    "text.total { font-size: 12px; }\n",
+  )
+  parent := map [string] string { "opacity": "0.5", "font-size": "9px", "fill": "gray" }
+
+  tests := [] struct {
+    name       string;
+    attributes map [string] string;
+    property   string;
+    want       string;
+  } {
+    { "rect",   map [string] string {},                               "fill",      "red" },
+    { "rect",   map [string] string { "fill": "white" },              "fill",      "red" },
+    { "rect",   map [string] string { "class": "total" },             "fill",      "green" },
+    { "rect",   map [string] string { "class": "cell" },              "fill",      "red" },
+    { "rect",   map [string] string { "style": "fill: white" },       "fill",      "white" },
+    { "circle", map [string] string {},                               "fill",      "gray" },
+    { "circle", map [string] string { "fill": "white" },              "fill",      "white" },
+    { "text",   map [string] string {},                               "font-size", "9px" },
+    { "text",   map [string] string { "class": "x total" },           "font-size", "12px" },
+    { "circle", map [string] string { "opacity": "0.5" },             "opacity",   "0.25" },
+    { "circle", map [string] string {},                               "opacity",   "0.5" },
+    { "circle", map [string] string { "stroke-width": "2" },          "stroke",    "" },
+  }
+
+  for _, test := range tests {
+    node  := & rasterNode { name: test.name, attributes: test.attributes }
+    style := rasterComputeStyle(node, parent, rules)
+    if style[test.property] != test.want {
+      t.Errorf("%s %v: %s is %q, want %q", test.name, test.attributes, test.property, style[test.property], test.want)
+    }
+  }
+}
+
+
+func TestSvgRasterize (t * testing.T) {
+  // A pixel to check, and its color
+  type pixel struct {
+    x, y int;
+    want color.RGBA;
+  }
+  red   := color.RGBA { 255, 0, 0, 255 }
+  white := color.RGBA { 255, 255, 255, 255 }
+  clear := color.RGBA {}
+
+  tests := [] struct {
+    name          string;
+    svg           string;
+    dpi           float64;
+    width, height int;
+    pixels        [] pixel;
+    err           bool;
+  } {
+    {
+      "a rect, at its size",
+      `<svg xmlns="http://www.w3.org/2000/svg" width="20" height="10" viewBox="0 0 20 10"><rect x="10" width="10" height="10" fill="#f00"/></svg>`,
+      96, 20, 10,
+      [] pixel { { 15, 5, red }, { 5, 5, clear } },
+      false,
+    },
+    {
+      "twice the dpi",
+      `<svg width="20" height="10" viewBox="0 0 20 10"><rect x="10" width="10" height="10" fill="#f00"/></svg>`,
+      192, 40, 20,
+      [] pixel { { 30, 10, red }, { 10, 10, clear } },
+      false,
+    },
+    {
+      "the height from the view box",
+      `<svg width="40" viewBox="0 0 20 10"><rect width="20" height="10" fill="red"/></svg>`,
+      96, 40, 20,
+      [] pixel { { 39, 19, red } },
+      false,
+    },
+    {
+      "the size of the view box",
+      `<svg viewBox="-10 -10 20 20"><circle r="5" fill="red"/></svg>`,
+      96, 20, 20,
+      [] pixel { { 10, 10, red }, { 1, 1, clear } },
+      false,
+    },
+    {
+      "styled by class, and by a style attribute over that",
+      `<svg viewBox="0 0 10 10"><style>.on { fill: red; }</style><rect class="on" width="10" height="10" style="fill: none"/><rect class="on" x="5" width="5" height="10"/></svg>`,
+      96, 10, 10,
+      [] pixel { { 2, 5, clear }, { 7, 5, red } },
+      false,
+    },
+    {
+      "hidden, and no fill",
+      `<svg viewBox="0 0 10 10"><rect width="10" height="10" fill="red" display="none"/><rect width="10" height="10" fill="none"/></svg>`,
+      96, 10, 10,
+      [] pixel { { 5, 5, clear } },
+      false,
+    },
+    {
+      "a full ring has a hole",
+      `<svg viewBox="-10 -10 20 20"><path fill="red" d="M 10 0 A 10 10 0 0 1 -10 0 A 10 10 0 0 1 10 0 Z M 5 0 A 5 5 0 0 0 -5 0 A 5 5 0 0 0 5 0 Z"/></svg>`,
+      96, 20, 20,
+      [] pixel { { 10, 10, clear }, { 10, 2, red }, { 17, 10, red } },
+      false,
+    },
+    {
+      "a polyline is filled, a line isn't",
+      `<svg viewBox="0 0 10 10"><line x1="0" y1="5" x2="10" y2="5" fill="red"/><polygon points="0,0 4,0 4,4 0,4" fill="red"/></svg>`,
+      96, 10, 10,
+      [] pixel { { 2, 2, red }, { 7, 5, clear } },
+      false,
+    },
+    { "not svg",        `<html/>`,                                      96, 0, 0, nil, true },
+    { "not xml",        `<svg viewBox="0 0 10 10">`,                    96, 0, 0, nil, true },
+    { "no size",        `<svg/>`,                                       96, 0, 0, nil, true },
+    { "too large",      `<svg width="4096" height="4096"/>`,            192, 0, 0, nil, true },
+    { "bad path",       `<svg viewBox="0 0 10 10"><path d="M 1"/></svg>`, 96, 0, 0, nil, true },
+  }
+
+  for _, test := range tests {
+    got, err := SvgRasterize([] byte(test.svg), test.dpi, nil)
+    if (err != nil) != test.err {
+      t.Errorf("%s: error %v", test.name, err)
+      continue
+    }
+    if err != nil { continue }
+
+    if got.Bounds().Dx() != test.width || got.Bounds().Dy() != test.height {
+      t.Errorf("%s: %v, want %dx%d", test.name, got.Bounds(), test.width, test.height)
+    }
+    for _, pixel := range test.pixels {
+      if at := got.RGBAAt(pixel.x, pixel.y); at != pixel.want {
+        t.Errorf("%s: %v at %d,%d, want %v", test.name, at, pixel.x, pixel.y, pixel.want)
+      }
+    }
+  }
+
+  // The background is under everything
+  got, err := SvgRasterize([] byte(`<svg viewBox="0 0 10 10"/>`), 96, color.White)
+  if err != nil { t.Fatal(err) }
+  if at := got.RGBAAt(5, 5); at != white {
+    t.Errorf("background: %v", at)
+  }
+
+  _, err = SvgRasterize([] byte(`<svg width="4096" height="4096"/>`), 192, nil)
+  if ! errors.Is(err, ErrRasterTooLarge) {
+    t.Errorf("too large: %v, want ErrRasterTooLarge", err)
+  }
+}
+
+
+func TestChartRenderPNG (t * testing.T) {
+  // Every chart rasterizes, at its size, on its theme's background, with no
+  // records or a few
+  records := [] ActivityRecord {
+    testRecord("Email",  "2026-03-02 09:00", "2026-03-02 10:00"),
+    testRecord("Coding", "2026-03-02 10:00", "2026-03-02 13:00"),
+  }
+
+  for _, name := range ChartNames() {
+    chart, err := LookupChart(name)
+    if err != nil { t.Fatal(err) }
+
+    for _, test_records := range [][] ActivityRecord { nil, records } {
+      var png_buffer bytes.Buffer
+      err := ChartRenderPNG(&png_buffer, chart, test_records, & ActivityRecordChartOptions { Width: "300", Theme: "dark" }, 96)
+      if err != nil {
+        t.Errorf("%s: %v", name, err)
+        continue
+      }
+      decoded, err := png.Decode(&png_buffer)
+      if err != nil {
+        t.Errorf("%s: %v", name, err)
+        continue
+      }
+      if decoded.Bounds().Dx() != 300 {
+        t.Errorf("%s: %d pixels wide, want 300", name, decoded.Bounds().Dx())
+      }
+      // The treemap's tiles reach the corner; with no records, nothing does
+      if r, g, b, _ := decoded.At(0, 0).RGBA(); test_records == nil && (r >> 8 != 0x22 || g >> 8 != 0x22 || b >> 8 != 0x22) {
+        t.Errorf("%s: corner %v, want the dark background", name, decoded.At(0, 0))
+      }
+    }
+  }
+}
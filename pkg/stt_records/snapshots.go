@@ -0,0 +1,301 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "fmt"
+  "os"
+  "path/filepath"
+  "sort"
+  "strings"
+  "time"
+)
+
+
+const STT_SNAPSHOT_PREFIX      string = "stt_records_"
+const STT_SNAPSHOT_SUFFIX      string = ".csv"
+const STT_SNAPSHOT_TIME_LAYOUT string = "20060102T150405Z"
+
+
+type SttSnapshot struct {
+  /*
+    A versioned copy of a downloaded STT export, named after the (UTC) time it
+    was taken.
+  */
+  Path string;
+  Time time.Time;
+}
+
+
+type ActivityRecordChange struct {
+  Old ActivityRecord;
+  New ActivityRecord;
+}
+
+
+type ActivityRecordsDiff struct {
+  Added   [] ActivityRecord;
+  Removed [] ActivityRecord;
+  Changed [] ActivityRecordChange;
+}
+
+
+func SttGetSnapshotDir () string {
+  snapshot_dir, ok := os.LookupEnv("STT_SNAPSHOT_DIR")
+  if ! ok {
+    return "stt_snapshots"
+  }
+  return snapshot_dir
+}
+
+
+func SttSnapshotList () (snapshots [] SttSnapshot, err error) {
+  /*
+    List the snapshots in the snapshot directory, oldest first. A missing
+    directory has no snapshots, and is not an error.
+  */
+
+  entries, err := os.ReadDir(SttGetSnapshotDir())
+  if err != nil {
+    if os.IsNotExist(err) { return nil, nil }
+    return nil, err
+  }
+
+  for _, entry := range entries {
+    name := entry.Name()
+    if entry.IsDir() { continue }
+    if ! strings.HasPrefix(name, STT_SNAPSHOT_PREFIX) { continue }
+    if ! strings.HasSuffix(name, STT_SNAPSHOT_SUFFIX) { continue }
+
+    time_str := strings.TrimSuffix(strings.TrimPrefix(name, STT_SNAPSHOT_PREFIX), STT_SNAPSHOT_SUFFIX)
+    snapshot_time, err := time.Parse(STT_SNAPSHOT_TIME_LAYOUT, time_str)
+    if err != nil { continue }
+
+    snapshots = append(snapshots, SttSnapshot {
+      Path: filepath.Join(SttGetSnapshotDir(), name),
+      Time: snapshot_time,
+    })
+  }
+
+  sort.Slice(snapshots, func (i, j int) bool {
+    return snapshots[i].Time.Before(snapshots[j].Time)
+  })
+
+  return snapshots, nil
+}
+
+
+func SttSnapshotSave (stt_path string) (snapshot * SttSnapshot, err error) {
+  /*
+    Copy the CSV at stt_path into the snapshot directory. If its contents are
+    identical to the newest snapshot, no copy is made and the newest snapshot
+    is returned instead.
+  */
+
+  contents, err := os.ReadFile(stt_path)
+  if err != nil { return nil, err }
+
+  snapshots, err := SttSnapshotList()
+  if err != nil { return nil, err }
+
+  if len(snapshots) > 0 {
+    latest := snapshots[len(snapshots)-1]
+    latest_contents, err := os.ReadFile(latest.Path)
+    if err == nil && bytes.Equal(contents, latest_contents) {
+      return &latest, nil
+    }
+  }
+
+  if err := os.MkdirAll(SttGetSnapshotDir(), 0755); err != nil {
+    return nil, err
+  }
+
+  snapshot_time := time.Now().UTC().Truncate(time.Second)
+  snapshot = &SttSnapshot {
+    Path: filepath.Join(
+      SttGetSnapshotDir(),
+      STT_SNAPSHOT_PREFIX + snapshot_time.Format(STT_SNAPSHOT_TIME_LAYOUT) + STT_SNAPSHOT_SUFFIX,
+    ),
+    Time: snapshot_time,
+  }
+
+  if err := os.WriteFile(snapshot.Path, contents, 0644); err != nil {
+    return nil, err
+  }
+
+  fmt.Println("Saved STT snapshot:", snapshot.Path)
+  return snapshot, nil
+}
+
+
+func SttSnapshotPrune () error {
+  /*
+    Delete snapshots beyond the retention limits: anything older than
+    STT_SNAPSHOT_MAX_AGE (when non-zero), and all but the newest
+    STT_SNAPSHOT_KEEP (when positive). The newest snapshot is always kept.
+  */
+
+  snapshots, err := SttSnapshotList()
+  if err != nil { return err }
+  if len(snapshots) == 0 { return nil }
+
+  now := time.Now()
+
+  for snapshot_i, snapshot := range snapshots[:len(snapshots)-1] {
+    too_many := STT_SNAPSHOT_KEEP > 0 && len(snapshots) - snapshot_i > STT_SNAPSHOT_KEEP
+    too_old  := STT_SNAPSHOT_MAX_AGE > 0 && now.Sub(snapshot.Time) > STT_SNAPSHOT_MAX_AGE
+
+    if ! too_many && ! too_old { continue }
+
+    if err := os.Remove(snapshot.Path); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+
+func (snapshot *SttSnapshot) Read () (records [] ActivityRecord, err error) {
+  csv_io_reader, err := os.Open(snapshot.Path)
+  if err != nil { return nil, err }
+  defer csv_io_reader.Close()
+
+  return SttCsvReadRange(csv_io_reader, nil, nil)
+}
+
+
+func ActivityRecordEqual (a, b ActivityRecord) bool {
+  if a.Activity_name    != b.Activity_name    { return false }
+  if a.Comment          != b.Comment          { return false }
+  if a.Record_tags      != b.Record_tags      { return false }
+  if a.Duration         != b.Duration         { return false }
+  if a.Duration_minutes != b.Duration_minutes { return false }
+  if ! a.Time_started.Equal(b.Time_started)   { return false }
+  if ! a.Time_ended.Equal(b.Time_ended)       { return false }
+
+  if len(a.Categories) != len(b.Categories) { return false }
+  for category_i := range a.Categories {
+    if a.Categories[category_i] != b.Categories[category_i] { return false }
+  }
+
+  return true
+}
+
+
+func activityRecordKeys (records [] ActivityRecord) [] string {
+  /*
+    STT exports have no record IDs, so records are identified by their start
+    time. Records sharing a start time are told apart by the order they appear
+    in.
+  */
+
+  keys := make([] string, len(records))
+  seen := make(map [string] int)
+
+  for record_i, record := range records {
+    start_str     := record.Time_started.Format(time.DateTime)
+    keys[record_i] = fmt.Sprintf("%s#%d", start_str, seen[start_str])
+    seen[start_str]++
+  }
+
+  return keys
+}
+
+
+func activityRecordDiffKeys (records [] ActivityRecord) map [string] ActivityRecord {
+  keyed := make(map [string] ActivityRecord, len(records))
+  for record_i, key := range activityRecordKeys(records) {
+    keyed[key] = records[record_i]
+  }
+  return keyed
+}
+
+
+func ActivityRecordsDiffRecords (old_records, new_records [] ActivityRecord) (diff ActivityRecordsDiff) {
+  /*
+    List the records added, removed and changed going from old_records to
+    new_records. Each list is in chronological order.
+
+    Records are matched by their start time (see activityRecordDiffKeys).
+    Then an added and a removed record of the same activity, ending at the
+    same time, are taken to be one record with its start time edited, and
+    listed as changed. Other edits to start times, such as moving a record
+    to another time altogether, are listed as one record removed and another
+    added.
+  */
+
+  old_keyed := activityRecordDiffKeys(old_records)
+  new_keyed := activityRecordDiffKeys(new_records)
+
+  for key, new_record := range new_keyed {
+    old_record, found := old_keyed[key]
+    if ! found {
+      diff.Added = append(diff.Added, new_record)
+    } else if ! ActivityRecordEqual(old_record, new_record) {
+      diff.Changed = append(diff.Changed, ActivityRecordChange { Old: old_record, New: new_record })
+    }
+  }
+
+  for key, old_record := range old_keyed {
+    if _, found := new_keyed[key]; ! found {
+      diff.Removed = append(diff.Removed, old_record)
+    }
+  }
+
+  sort.Slice(diff.Added, func (i, j int) bool {
+    return diff.Added[i].Time_started.Before(diff.Added[j].Time_started)
+  })
+  sort.Slice(diff.Removed, func (i, j int) bool {
+    return diff.Removed[i].Time_started.Before(diff.Removed[j].Time_started)
+  })
+
+  // Pair up records whose start times were edited
+
+  REMOVED_SEARCH:
+  for removed_i := 0; removed_i < len(diff.Removed); removed_i++ {
+    old_record := diff.Removed[removed_i]
+    for added_i, new_record := range diff.Added {
+      if new_record.Activity_name != old_record.Activity_name { continue }
+      if ! new_record.Time_ended.Equal(old_record.Time_ended)  { continue }
+
+      diff.Changed = append(diff.Changed, ActivityRecordChange { Old: old_record, New: new_record })
+      diff.Added   = append(diff.Added[:added_i], diff.Added[added_i + 1:]...)
+      diff.Removed = append(diff.Removed[:removed_i], diff.Removed[removed_i + 1:]...)
+      removed_i--
+      continue REMOVED_SEARCH
+    }
+  }
+  sort.Slice(diff.Changed, func (i, j int) bool {
+    return diff.Changed[i].New.Time_started.Before(diff.Changed[j].New.Time_started)
+  })
+
+  return diff
+}
+
+
+func SttSnapshotDiffLatest () (diff ActivityRecordsDiff, old_snapshot, new_snapshot * SttSnapshot, err error) {
+  /*
+    Diff the two newest snapshots, i.e. what changed with the last sync which
+    downloaded something new. With fewer than two snapshots, the snapshots
+    which don't exist are nil and every record of the newest one is added.
+  */
+
+  snapshots, err := SttSnapshotList()
+  if err != nil { return }
+  if len(snapshots) == 0 { return }
+
+  new_snapshot = &snapshots[len(snapshots)-1]
+  new_records, err := new_snapshot.Read()
+  if err != nil { return }
+
+  var old_records [] ActivityRecord
+  if len(snapshots) >= 2 {
+    old_snapshot = &snapshots[len(snapshots)-2]
+    old_records, err = old_snapshot.Read()
+    if err != nil { return }
+  }
+
+  diff = ActivityRecordsDiffRecords(old_records, new_records)
+  return diff, old_snapshot, new_snapshot, nil
+}
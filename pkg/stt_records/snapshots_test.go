@@ -0,0 +1,65 @@
+package stt_records;
+
+
+import (
+  "testing"
+  "time"
+)
+
+
+func testRecord (activity_name string, start_str, end_str string) ActivityRecord {
+  /*
+    A record of an activity between two "2006-01-02 15:04" times, in UTC.
+  */
+  start, err := time.Parse("2006-01-02 15:04", start_str)
+  if err != nil { panic(err) }
+  end, err := time.Parse("2006-01-02 15:04", end_str)
+  if err != nil { panic(err) }
+
+  return ActivityRecord {
+    Activity_name:    activity_name,
+    Time_started:     start,
+    Time_ended:       end,
+    Duration:         end.Sub(start),
+    Duration_minutes: uint(end.Sub(start).Minutes()),
+  }
+}
+
+
+func TestActivityRecordsDiffRecords (t * testing.T) {
+  email    := testRecord("Email",   "2026-03-02 09:00", "2026-03-02 10:00")
+  meeting  := testRecord("Meeting", "2026-03-02 10:00", "2026-03-02 11:00")
+  coding   := testRecord("Coding",  "2026-03-02 11:00", "2026-03-02 12:00")
+
+  email_commented := email
+  email_commented.Comment = "inbox zero"
+  meeting_earlier := testRecord("Meeting", "2026-03-02 09:30", "2026-03-02 11:00")
+  meeting_moved   := testRecord("Meeting", "2026-03-02 14:00", "2026-03-02 15:00")
+
+  tests := [] struct {
+    name         string;
+    old_records  [] ActivityRecord;
+    new_records  [] ActivityRecord;
+    added        int;
+    removed      int;
+    changed      int;
+  } {
+    { "unchanged",            [] ActivityRecord { email, meeting }, [] ActivityRecord { email, meeting },           0, 0, 0 },
+    { "added",                [] ActivityRecord { email },          [] ActivityRecord { email, meeting },           1, 0, 0 },
+    { "removed",              [] ActivityRecord { email, meeting }, [] ActivityRecord { meeting },                  0, 1, 0 },
+    { "comment edited",       [] ActivityRecord { email, meeting }, [] ActivityRecord { email_commented, meeting }, 0, 0, 1 },
+    { "start edited",         [] ActivityRecord { email, meeting }, [] ActivityRecord { email, meeting_earlier },   0, 0, 1 },
+    { "moved",                [] ActivityRecord { email, meeting }, [] ActivityRecord { email, meeting_moved },     1, 1, 0 },
+    { "added, start edited",  [] ActivityRecord { meeting },        [] ActivityRecord { meeting_earlier, coding },  1, 0, 1 },
+  }
+
+  for _, test := range tests {
+    diff := ActivityRecordsDiffRecords(test.old_records, test.new_records)
+    if len(diff.Added) != test.added || len(diff.Removed) != test.removed || len(diff.Changed) != test.changed {
+      t.Errorf(
+        "%s: got %d added, %d removed, %d changed; want %d, %d, %d",
+        test.name, len(diff.Added), len(diff.Removed), len(diff.Changed), test.added, test.removed, test.changed,
+      )
+    }
+  }
+}
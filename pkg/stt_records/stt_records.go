@@ -11,9 +11,8 @@ import (
   "encoding/csv"
   "strconv"
   "strings"
+  "sort"
   re "regexp"
-  "math"
-  "math/rand"
 )
 
 
@@ -32,6 +31,10 @@ var STT_CSV_COLUMNS [] string = [] string {
 var SttInitialized bool          = false
 var STT_DAY_OFFSET time.Duration = 0
 var STT_TIMEZONE * time.Location = nil
+var STT_WEEK_START time.Weekday  = time.Monday
+
+var STT_SNAPSHOT_KEEP    int           = 30
+var STT_SNAPSHOT_MAX_AGE time.Duration = 0
 
 type ActivityRecord struct {
   /*
@@ -61,6 +64,30 @@ func SttInit () (err error) {
     if err != nil { return err }
   }
 
+  week_start_str, found := os.LookupEnv("STT_WEEK_START")
+  if found {
+    STT_WEEK_START, err = ParseWeekday(week_start_str)
+    if err != nil { return err }
+  }
+
+  snapshot_keep_str, found := os.LookupEnv("STT_SNAPSHOT_KEEP")
+  if found {
+    STT_SNAPSHOT_KEEP, err = strconv.Atoi(snapshot_keep_str)
+    if err != nil { return err }
+  }
+
+  snapshot_max_age_str, found := os.LookupEnv("STT_SNAPSHOT_MAX_AGE")
+  if found {
+    STT_SNAPSHOT_MAX_AGE, err = time.ParseDuration(snapshot_max_age_str)
+    if err != nil { return err }
+  }
+
+  colors_str, found := os.LookupEnv("STT_COLORS")
+  if found {
+    STT_COLORS, err = ParseColorOverrides(colors_str)
+    if err != nil { return err }
+  }
+
   SttInitialized = true
   return nil
 }
@@ -77,21 +104,76 @@ func (record *ActivityRecord) DayStart () time.Time {
 }
 
 
+func (record *ActivityRecord) Tags () [] string {
+  /*
+    The record's tags, which STT exports as a comma-separated list.
+  */
+  tags := make([] string, 0)
+  for _, tag := range strings.Split(record.Record_tags, ",") {
+    tag = strings.TrimSpace(tag)
+    if tag != "" {
+      tags = append(tags, tag)
+    }
+  }
+  return tags
+}
+
+
 type ActivityRecordChartOptions struct {
   Width  string;
   Height string;
+
+  // The chart's accessible name, for screen readers and as its title; each
+  // chart has its own otherwise, like "Time by activity"
+  Title string;
+
+  // What charts with series (and the pie's slices) split them by: "activity"
+  // (the default), "category" or "tag". The timeline also takes "day", for a
+  // row per day.
+  Group_by string;
+
+  // The windows, in days, of the rolling averages the line chart draws, such
+  // as 7 and 28; it draws daily totals alone without any longer than a day
+  Rolling_days [] int;
+
+  // Whether the clock chart averages its records' days into a typical day
+  Average bool;
+
+  // The background the chart's colors are for: "light" (the default) or
+  // "dark" (see ColorVariant)
+  Theme string;
+
+  // Whether the pie chart has a legend, the percentage under which its
+  // slices are folded into one "Other" slice, and the Order of its slices:
+  // "size" (the default, largest first) or "name"
+  Legend        bool;
+  Other_percent float64;
+  Order         string;
+
+  // Whether the pie chart is a donut, with the total in its middle; records
+  // from another period (named by Compare_label) to draw as a ring inside
+  // the pie's, in the same colors; and the activities' target proportions
+  // (see ParsePieTargets), to draw as a ring around it
+  Donut           bool;
+  Compare_records [] ActivityRecord;
+  Compare_label   string;
+  Targets         map [string] float64;
+
+  // The longest gap between two records that the transitions chart counts
+  // as a transition from one to the other; TRANSITIONS_MAX_GAP if nil
+  Max_gap * time.Duration;
 }
 
 
 func downloadFile (output_path, url string) (bytes_written int64, err error) {
   bytes_written = 0
   output_file, err := os.Create(output_path)
-  defer output_file.Close()
   if err != nil { return }
+  defer output_file.Close()
 
   response, err := http.Get(url)
-  defer response.Body.Close()
   if err != nil { return }
+  defer response.Body.Close()
 
   if response.StatusCode != http.StatusOK {
     return 0, fmt.Errorf("bad status: %s", response.Status)
@@ -140,7 +222,7 @@ func SttSync () (err error, was_downloaded bool) {
 
   // Look at the file, if it exists, and whether to download the STT CSV file.
 
-  if stat, err := os.Stat("stt_records.csv"); err == nil {
+  if stat, err := os.Stat(stt_path); err == nil {
     // The file exists; if it's old enough, set the do_download variable flag
     now := time.Now()
     var stt_age_hours float64 = now.Sub(stat.ModTime()).Hours()
@@ -158,13 +240,24 @@ func SttSync () (err error, was_downloaded bool) {
 
     records_num_bytes, err := downloadFile(stt_path, stt_url)
     if err != nil {
-      return fmt.Errorf("Could not download STT records:", err), false
+      return fmt.Errorf("Could not download STT records: %w", err), false
     }
 
     was_downloaded = true
     fmt.Printf("Downloaded STT records (%d)\n", records_num_bytes)
   }
 
+  // Keep a copy of every distinct download, so that retroactive edits on the
+  // phone can be found by diffing snapshots. Unchanged files aren't copied.
+
+  if _, err := SttSnapshotSave(stt_path); err != nil {
+    return fmt.Errorf("Could not snapshot STT records: %w", err), was_downloaded
+  }
+
+  if err := SttSnapshotPrune(); err != nil {
+    return fmt.Errorf("Could not prune STT snapshots: %w", err), was_downloaded
+  }
+
   return nil, was_downloaded
 }
 
@@ -246,8 +339,8 @@ func SttCsvReadRange (
     // Create the ActivityRecord struct
 
     var activity_name string = row[column_indices["activity name"]]
-    var comment       string = row[column_indices["comments"]]
-    var record_tags   string = row[column_indices["record_tags"]]
+    var comment       string = row[column_indices["comment"]]
+    var record_tags   string = row[column_indices["record tags"]]
 
     duration_minutes_signed, err := strconv.Atoi(row[column_indices["duration minutes"]])
     if err != nil { continue }
@@ -284,6 +377,90 @@ func SttCsvReadRange (
 }
 
 
+func sttCsvFormatDuration (duration time.Duration) string {
+  seconds := int64(duration / time.Second)
+  return fmt.Sprintf("%02d:%02d:%02d", seconds / 3600, (seconds / 60) % 60, seconds % 60)
+}
+
+
+func SttCsvWrite (io_writer io.Writer, records [] ActivityRecord) (err error) {
+  /*
+    Serialize records into the STT export CSV format, with the columns in the
+    order of STT_CSV_COLUMNS, such that SttCsvReadRange reads back the same
+    records.
+  */
+
+  csv_writer := csv.NewWriter(io_writer)
+
+  if err = csv_writer.Write(STT_CSV_COLUMNS); err != nil { return err }
+
+  for _, record := range records {
+    row := [] string {
+      record.Activity_name,
+      record.Time_started.Format(time.DateTime),
+      record.Time_ended.Format(time.DateTime),
+      record.Comment,
+      strings.Join(record.Categories, ", "),
+      record.Record_tags,
+      sttCsvFormatDuration(record.Duration),
+      strconv.FormatUint(uint64(record.Duration_minutes), 10),
+    }
+
+    if err = csv_writer.Write(row); err != nil { return err }
+  }
+
+  csv_writer.Flush()
+  return csv_writer.Error()
+}
+
+
+func SttCsvWriteFile (output_path string, records [] ActivityRecord) (err error) {
+  output_file, err := os.Create(output_path)
+  if err != nil { return err }
+
+  err = SttCsvWrite(output_file, records)
+  if close_err := output_file.Close(); err == nil {
+    err = close_err
+  }
+  return err
+}
+
+
+func ActivityRecordsMerge (record_sets ...[] ActivityRecord) [] ActivityRecord {
+  /*
+    Merge sets of records, such as several snapshots or exports from more
+    than one phone, into one chronological set. Records are matched the same
+    way as ActivityRecordsDiffRecords matches them; where sets disagree about
+    a record, the later set wins.
+  */
+
+  // Records are kept in the order they're first seen, so that those sharing
+  // a start time come out the same way every time
+
+  merged := make(map [string] ActivityRecord)
+  var keys [] string
+  for _, records := range record_sets {
+    for record_i, key := range activityRecordKeys(records) {
+      if _, found := merged[key]; ! found {
+        keys = append(keys, key)
+      }
+      merged[key] = records[record_i]
+    }
+  }
+
+  records := make([] ActivityRecord, 0, len(keys))
+  for _, key := range keys {
+    records = append(records, merged[key])
+  }
+
+  sort.SliceStable(records, func (i, j int) bool {
+    return records[i].Time_started.Before(records[j].Time_started)
+  })
+
+  return records
+}
+
+
 func ActivityRecordsFilterCategories (records [] ActivityRecord, categories ...string) [] ActivityRecord {
   filtered := make([] ActivityRecord, len(records))
   count    := 0
@@ -320,169 +497,3 @@ func ActivityRecordsFilterTimeRange (records [] ActivityRecord, after_date, befo
   return filtered[:count]
 }
 
-
-func ActivityRecordsPlotPieChart (records [] ActivityRecord, options * ActivityRecordChartOptions) string {
-  //
-  // Get sums of minutes for each activity name, and calculate their "pie
-  // slice" ratio by dividing them by the sum of all activity minutes across
-  // records.
-  //
-
-  if options == nil {
-    options = & ActivityRecordChartOptions {
-      Width: "400",
-      Height: "220",
-    }
-  }
-
-  activity_minutes := make(map [string] uint)
-  var records_duration uint = 0
-
-  for _, record := range records {
-    name                     := record.Activity_name
-    record_duration          := record.Duration_minutes
-    activity_duration, found := activity_minutes[name]
-
-    if found {
-      activity_minutes[name] = activity_duration + record_duration
-    } else {
-      activity_minutes[name] = record_duration
-    }
-
-    records_duration += record_duration
-  }
-
-  //
-  // Generate an SVG root and styles
-  //
-
-  var pie_svg  strings.Builder
-
-  if options.Width != "" || options.Height != "" {
-    fmt.Fprintf(
-      &pie_svg,
-      `<svg width="%s" height="%s" viewBox="-2 -1.1 4 2.2" xmlns="http://www.w3.org/2000/svg">` + "\n" +
-      "  <style>\n" +
-      "    path { transition: all 0.25s; stroke-width: 0.01; stroke: #8880; }\n " +
-      "    path:hover { transform: scale(1.075); filter: brightness(1.1); stroke-width: 0.01; stroke: #8888; }\n" +
-      "  </style>\n",
-      options.Width,
-      options.Height,
-    )
-  } else {
-    fmt.Fprintf(
-      &pie_svg,
-      `<svg viewBox="-2 -1.1 4 2.2" xmlns="http://www.w3.org/2000/svg">` + "\n" +
-      "  <style>\n" +
-      "    path { transition: all 1s; }\n " +
-      "    path:hover { transform: scale(1.075); filter: brightness(1.1); stroke-width: 0.01; stroke: #888; }\n" +
-      "  </style>\n",
-    )
-  }
-
-  //
-  // Generate activity pie slice geometry
-  //
-
-  type ChartSlice struct {
-    name       string;
-    minutes      uint;
-    ratio     float64;
-    angle     float64;
-    center_t  float64;
-
-    start_t, start_x, start_y float64;
-    end_t,   end_x,   end_y   float64;
-
-    fill       string;
-  }
-
-  pie := make([] ChartSlice, 0)
-  var pie_head float64 = 0  // keep track of the angle as we create slices
-
-  for name, minutes := range activity_minutes {
-    slice := ChartSlice {
-      name:    name,
-      minutes: minutes,
-      ratio:   float64(minutes) / float64(records_duration),
-      start_t: pie_head,
-    }
-
-    slice.angle     = 2 * math.Pi * slice.ratio
-    slice.end_t     = pie_head + slice.angle
-
-    slice.center_t  = slice.start_t + slice.angle/2
-
-    pie_head        = slice.end_t
-
-    slice.start_x   = math.Cos(slice.start_t)
-    slice.start_y   = math.Sin(slice.start_t)
-
-    slice.end_x     = math.Cos(slice.end_t)
-    slice.end_y     = math.Sin(slice.end_t)
-
-    rand.Seed(time.Now().UnixNano())
-    r := 128 + rand.Intn(100)
-    g := 128 + rand.Intn(100)
-    b := 128 + rand.Intn(100)
-    slice.fill = fmt.Sprintf(`#%02x%02x%02x`, r % 256, g % 256, b % 256)
-
-    pie = append(pie, slice)
-  }
-
-  //
-  // Generate SVG slice paths
-  //
-
-  for _, slice := range pie {
-    var large_arc_flag string = "1"
-    if slice.end_t - slice.start_t <= math.Pi {
-      large_arc_flag = "0"
-    }
-
-    fmt.Fprintf(
-      &pie_svg,
-      "  <!-- %s: %dm, %2.1f%%, %3.1f° -->\n" +
-      `  <path d="M 0 0 L %f %f A 1 1 0 %s 0 %f %f Z" fill="%s"/>` + "\n",
-      slice.name,
-      slice.minutes,
-      slice.ratio * 100,
-      slice.ratio * 360,
-
-      slice.end_x,   slice.end_y,
-      large_arc_flag,
-      slice.start_x, slice.start_y,
-      slice.fill,
-    )
-  }
-  fmt.Fprintf(&pie_svg, "\n")
-
-  //
-  // Generate SVG pie slice text labels
-  //
-
-  for _, slice := range pie {
-    text_x := math.Cos(slice.center_t) * 1.5
-    text_y := math.Min(math.Max(math.Sin(slice.center_t) * 1.35, -1.0), 0.9)
-
-    fmt.Fprintf(
-      &pie_svg,
-      `  <text x="%f" y="%f" font-family="sans-serif" font-size="0.1" text-anchor="middle">%s (%2.1f%%)</text>` + "\n",
-      text_x, text_y,
-      slice.name, slice.ratio * 100,
-    )
-
-    fmt.Fprintf(
-      &pie_svg,
-      `  <text x="%f" y="%f" font-family="sans-serif" font-size="0.08" text-anchor="middle">%s</text>` + "\n",
-      text_x, text_y + 0.1,
-      minutesFormatDuration(slice.minutes),
-    )
-
-    fmt.Fprintf(&pie_svg, "\n")
-  }
-
-
-  pie_svg.WriteString(`</svg>`)
-  return pie_svg.String()
-}
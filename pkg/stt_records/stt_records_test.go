@@ -0,0 +1,69 @@
+package stt_records;
+
+
+import (
+  "bytes"
+  "testing"
+)
+
+
+func TestSttCsvRoundTrip (t * testing.T) {
+  records := [] ActivityRecord {
+    testRecord("Email",       "2026-03-02 09:00", "2026-03-02 10:15"),
+    testRecord("Code review", "2026-03-02 10:15", "2026-03-02 12:00"),
+    testRecord("Sleep",       "2026-03-02 23:30", "2026-03-03 07:00"),
+  }
+  records[0].Categories  = [] string { "Productivity" }
+  records[0].Comment     = `replies, "urgent" ones first`
+  records[1].Categories  = [] string { "Productivity", "Development" }
+  records[1].Record_tags = "client=acme, deep"
+  records[1].Comment     = "line one\nline two"
+  records[2].Categories  = [] string { "Health" }
+
+  var csv_buffer bytes.Buffer
+  if err := SttCsvWrite(&csv_buffer, records); err != nil {
+    t.Fatal(err)
+  }
+  read_records, err := SttCsvReadRange(&csv_buffer, nil, nil)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if len(read_records) != len(records) {
+    t.Fatalf("read %d records, want %d", len(read_records), len(records))
+  }
+  for record_i := range records {
+    if ! ActivityRecordEqual(read_records[record_i], records[record_i]) {
+      t.Errorf("record %d: read %+v, want %+v", record_i, read_records[record_i], records[record_i])
+    }
+  }
+}
+
+
+func TestActivityRecordsMerge (t * testing.T) {
+  email   := testRecord("Email",   "2026-03-02 09:00", "2026-03-02 10:00")
+  meeting := testRecord("Meeting", "2026-03-02 09:00", "2026-03-02 09:30")
+  coding  := testRecord("Coding",  "2026-03-02 08:00", "2026-03-02 09:00")
+
+  email_commented := email
+  email_commented.Comment = "edited"
+
+  want := [] ActivityRecord { coding, email_commented, meeting }
+
+  // Records sharing a start time come out in the order they were first seen,
+  // every time, and the later set's version of a record wins
+  for run := 0; run < 20; run++ {
+    merged := ActivityRecordsMerge(
+      [] ActivityRecord { email, meeting },
+      [] ActivityRecord { coding, email_commented },
+    )
+    if len(merged) != len(want) {
+      t.Fatalf("merged %d records, want %d", len(merged), len(want))
+    }
+    for record_i := range want {
+      if ! ActivityRecordEqual(merged[record_i], want[record_i]) {
+        t.Fatalf("run %d, record %d: got %+v, want %+v", run, record_i, merged[record_i], want[record_i])
+      }
+    }
+  }
+}
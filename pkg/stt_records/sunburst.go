@@ -0,0 +1,185 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+)
+
+
+type chartBranch struct {
+  /*
+    A category, with its total and its activities' totals, largest first.
+  */
+  name     string;
+  minutes  uint;
+  children [] AggregateGroup;
+}
+
+
+func activityRecordsHierarchy (records [] ActivityRecord) (branches [] chartBranch, total_minutes uint) {
+  /*
+    Records' minutes by category, then activity, largest first. A record with
+    several categories counts toward each of them.
+  */
+
+  category_groups, _ := ActivityRecordsAggregate(records, "category")
+  AggregateGroupsSortBySum(category_groups)
+
+  branch_indices := make(map [string] int, len(category_groups))
+  for _, group := range category_groups {
+    branch_indices[group.Keys[0]] = len(branches)
+    branches = append(branches, chartBranch { name: group.Keys[0], minutes: group.Minutes })
+    total_minutes += group.Minutes
+  }
+
+  activity_groups, _ := ActivityRecordsAggregate(records, "category", "activity")
+  AggregateGroupsSortBySum(activity_groups)
+  for _, group := range activity_groups {
+    branch := &branches[branch_indices[group.Keys[0]]]
+    branch.children = append(branch.children, group)
+  }
+
+  return branches, total_minutes
+}
+
+
+func chartPercent (minutes, total_minutes uint) float64 {
+  if total_minutes == 0 { return 0 }
+  return 100 * float64(minutes) / float64(total_minutes)
+}
+
+
+func activityRecordsSunburstSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot a ring of categories, with a ring of each category's activities
+    around it, with each segment's angle in proportion to its minutes, and the
+    total in the middle. Segments large enough are labelled.
+  */
+
+  options = svgChartOptions(options, "300", "300")
+  branches, total_minutes := activityRecordsHierarchy(records)
+
+  // Geometry, in viewBox units: the rings around the origin
+
+  const hole_r    float64 = 30
+  const inner_r   float64 = 75
+  const outer_r   float64 = 125
+  const min_label float64 = 0.2  // radians, for a segment to be labelled
+
+  category_groups, _ := ActivityRecordsAggregate(records, "category")
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { -130, -130, 260, 260 },
+    "Time by category and activity", chartDescribeRecords(records, category_groups),
+    "    path { stroke: #fff; stroke-width: 0.75; }\n" +
+    "    path:hover { filter: brightness(1.1); stroke: #555; }\n" +
+    "    text { font-family: sans-serif; font-size: 7px; pointer-events: none; }\n" +
+    "    text.total { font-size: 11px; fill: #333; }\n",
+  )
+  addTotal := func () {
+    svg.Add("text").Attr("class", "total").Attr("text-anchor", "middle").Attr("dominant-baseline", "middle").
+      Text(minutesFormatDuration(total_minutes))
+  }
+
+  // With no time to divide, there are no segments, just the total
+  if total_minutes == 0 {
+    addTotal()
+    return svg
+  }
+
+  angle := func (minutes uint) float64 {
+    return 2 * math.Pi * float64(minutes) / float64(total_minutes)
+  }
+  addLabel := func (name string, start_t, end_t, label_r float64) {
+    if end_t - start_t < min_label { return }
+    center_t := (start_t + end_t) / 2
+    svg.Add("text").
+      Num("x", label_r * math.Cos(center_t)).Num("y", label_r * math.Sin(center_t)).
+      Attr("text-anchor", "middle").Attr("dominant-baseline", "middle").
+      Attr("fill", ColorContrastText(chartColor(options, name))).
+      Text(name)
+  }
+
+  // Segments, clockwise from the top, then their labels over them
+
+  start_t := -math.Pi / 2
+
+  for _, branch := range branches {
+    end_t := start_t + angle(branch.minutes)
+
+    svg.Add("path").Attr("class", "category").
+      Path(svgArcPath(start_t, end_t, hole_r, inner_r)).
+      Attr("fill", chartColor(options, branch.name)).
+      Title(fmt.Sprintf(
+        "%s: %s (%.1f%%)",
+        branch.name, minutesFormatDuration(branch.minutes), chartPercent(branch.minutes, total_minutes),
+      ))
+
+    child_start_t := start_t
+    for _, child := range branch.children {
+      child_end_t := child_start_t + angle(child.Minutes)
+      svg.Add("path").Attr("class", "activity").
+        Path(svgArcPath(child_start_t, child_end_t, inner_r, outer_r)).
+        Attr("fill", chartColor(options, child.Keys[1])).
+        Title(fmt.Sprintf(
+          "%s › %s: %s (%.1f%% of %s, %.1f%% overall)",
+          branch.name, child.Keys[1], minutesFormatDuration(child.Minutes),
+          chartPercent(child.Minutes, branch.minutes), branch.name,
+          chartPercent(child.Minutes, total_minutes),
+        ))
+      child_start_t = child_end_t
+    }
+
+    start_t = end_t
+  }
+
+  start_t = -math.Pi / 2
+  for _, branch := range branches {
+    end_t := start_t + angle(branch.minutes)
+    addLabel(branch.name, start_t, end_t, (hole_r + inner_r) / 2)
+
+    child_start_t := start_t
+    for _, child := range branch.children {
+      child_end_t := child_start_t + angle(child.Minutes)
+      addLabel(child.Keys[1], child_start_t, child_end_t, (inner_r + outer_r) / 2)
+      child_start_t = child_end_t
+    }
+    start_t = end_t
+  }
+
+  addTotal()
+
+  return svg
+}
+
+
+func activityRecordsHierarchyTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per category, then a row per activity in it, with their times and
+    shares of the total, for the sunburst and the treemap.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  branches, total_minutes := activityRecordsHierarchy(records)
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Time by category and activity"),
+    Columns: [] string { "Category", "Activity", "Time", "Share" },
+    Footer:  [] string { "Total", "", minutesFormatDuration(total_minutes), "" },
+  }
+  for _, branch := range branches {
+    table.Rows = append(table.Rows, [] string {
+      branch.name, "", minutesFormatDuration(branch.minutes), chartTablePercent(chartPercent(branch.minutes, total_minutes)),
+    })
+    for _, child := range branch.children {
+      table.Rows = append(table.Rows, [] string {
+        branch.name, child.Keys[1], minutesFormatDuration(child.Minutes), chartTablePercent(chartPercent(child.Minutes, total_minutes)),
+      })
+    }
+  }
+  return table
+}
@@ -0,0 +1,345 @@
+package stt_records;
+
+
+import (
+  "html"
+  "io"
+  "math"
+  "strconv"
+  "strings"
+)
+
+
+//
+// A small typed SVG builder. Elements hold their attributes and children as
+// values, and text and attribute values are escaped as they're written, so
+// that names and comments from records can't break out of the document.
+// Numbers are written at the precision of the root element.
+//
+
+
+const SVG_PRECISION_DEFAULT int = 2
+
+
+type svgValueKind int
+
+const (
+  SVG_VALUE_TEXT   svgValueKind = iota
+  SVG_VALUE_NUMBER
+  SVG_VALUE_POINTS
+  SVG_VALUE_PATH
+)
+
+
+type svgAttribute struct {
+  name    string;
+  kind    svgValueKind;
+  text    string;
+  numbers [] float64;
+  path  * SvgPath;
+}
+
+
+type svgNode struct {
+  /*
+    A child of an element: either another element, or a text node.
+  */
+  element * SvgElement;
+  text      string;
+}
+
+
+type SvgElement struct {
+  /*
+    Precision is the number of decimal places numbers are written with, for
+    the element and its descendants, when it's the one being written.
+  */
+  Name       string;
+  Precision  int;
+
+  attributes [] svgAttribute;
+  children   [] svgNode;
+}
+
+
+func SvgNew (name string) * SvgElement {
+  return & SvgElement { Name: name, Precision: SVG_PRECISION_DEFAULT }
+}
+
+
+func SvgRoot (view_box_x, view_box_y, view_box_width, view_box_height float64) * SvgElement {
+  /*
+    A root svg element with the SVG namespace and a view box.
+  */
+  return SvgNew("svg").
+    Attr("xmlns", "http://www.w3.org/2000/svg").
+    setNumbers("viewBox", SVG_VALUE_NUMBER, view_box_x, view_box_y, view_box_width, view_box_height)
+}
+
+
+func (element * SvgElement) setAttribute (attribute svgAttribute) * SvgElement {
+  for attribute_i := range element.attributes {
+    if element.attributes[attribute_i].name == attribute.name {
+      element.attributes[attribute_i] = attribute
+      return element
+    }
+  }
+  element.attributes = append(element.attributes, attribute)
+  return element
+}
+
+
+func (element * SvgElement) setNumbers (name string, kind svgValueKind, numbers ...float64) * SvgElement {
+  return element.setAttribute(svgAttribute { name: name, kind: kind, numbers: numbers })
+}
+
+
+func (element * SvgElement) Attr (name, value string) * SvgElement {
+  return element.setAttribute(svgAttribute { name: name, kind: SVG_VALUE_TEXT, text: value })
+}
+
+
+func (element * SvgElement) Num (name string, value float64) * SvgElement {
+  return element.setNumbers(name, SVG_VALUE_NUMBER, value)
+}
+
+
+func (element * SvgElement) Points (name string, coordinates ...float64) * SvgElement {
+  /*
+    Set an attribute to a list of points, as in a polyline's "points", from
+    x and y coordinates in turn.
+  */
+  return element.setNumbers(name, SVG_VALUE_POINTS, coordinates...)
+}
+
+
+func (element * SvgElement) Path (path * SvgPath) * SvgElement {
+  return element.setAttribute(svgAttribute { name: "d", kind: SVG_VALUE_PATH, path: path })
+}
+
+
+func (element * SvgElement) Add (name string) * SvgElement {
+  /*
+    Append a new child element, and return it.
+  */
+  child := SvgNew(name)
+  element.children = append(element.children, svgNode { element: child })
+  return child
+}
+
+
+func (element * SvgElement) Append (children ...* SvgElement) * SvgElement {
+  for _, child := range children {
+    element.children = append(element.children, svgNode { element: child })
+  }
+  return element
+}
+
+
+func (element * SvgElement) Text (text string) * SvgElement {
+  element.children = append(element.children, svgNode { text: text })
+  return element
+}
+
+
+func (element * SvgElement) Title (title string) * SvgElement {
+  /*
+    Add a title child, which browsers show on hover.
+  */
+  element.Add("title").Text(title)
+  return element
+}
+
+
+func svgFormatNumber (value float64, precision int) string {
+  /*
+    A number at the given precision, without trailing zeros.
+  */
+  if math.IsNaN(value) || math.IsInf(value, 0) {
+    value = 0
+  }
+  number := strconv.FormatFloat(value, 'f', precision, 64)
+  if strings.Contains(number, ".") {
+    number = strings.TrimRight(strings.TrimRight(number, "0"), ".")
+  }
+  if number == "-0" {
+    number = "0"
+  }
+  return number
+}
+
+
+func (attribute * svgAttribute) value (precision int) string {
+  switch attribute.kind {
+  case SVG_VALUE_NUMBER:
+    numbers := make([] string, len(attribute.numbers))
+    for number_i, number := range attribute.numbers {
+      numbers[number_i] = svgFormatNumber(number, precision)
+    }
+    return strings.Join(numbers, " ")
+
+  case SVG_VALUE_POINTS:
+    var points strings.Builder
+    for number_i := 0; number_i + 1 < len(attribute.numbers); number_i += 2 {
+      if number_i > 0 {
+        points.WriteString(" ")
+      }
+      points.WriteString(svgFormatNumber(attribute.numbers[number_i], precision))
+      points.WriteString(",")
+      points.WriteString(svgFormatNumber(attribute.numbers[number_i + 1], precision))
+    }
+    return points.String()
+
+  case SVG_VALUE_PATH:
+    return attribute.path.Format(precision)
+  }
+  return attribute.text
+}
+
+
+type svgWriter struct {
+  /*
+    Writes to a writer, keeping count, and stopping at the first error.
+  */
+  writer  io.Writer;
+  written int64;
+  err     error;
+}
+
+
+func (svg * svgWriter) WriteString (text string) {
+  if svg.err != nil { return }
+  written, err := io.WriteString(svg.writer, text)
+  svg.written += int64(written)
+  svg.err      = err
+}
+
+
+func (element * SvgElement) write (svg * svgWriter, precision int, indent string) {
+  svg.WriteString(indent)
+  svg.WriteString("<")
+  svg.WriteString(element.Name)
+  for attribute_i := range element.attributes {
+    attribute := &element.attributes[attribute_i]
+    svg.WriteString(" ")
+    svg.WriteString(attribute.name)
+    svg.WriteString(`="`)
+    svg.WriteString(html.EscapeString(attribute.value(precision)))
+    svg.WriteString(`"`)
+  }
+
+  if len(element.children) == 0 {
+    svg.WriteString("/>")
+    return
+  }
+  svg.WriteString(">")
+
+  // Elements with only text keep it inline; others get a line per child
+  inline := true
+  for _, child := range element.children {
+    if child.element != nil {
+      inline = false
+    }
+  }
+
+  for _, child := range element.children {
+    if child.element == nil {
+      svg.WriteString(html.EscapeString(child.text))
+      continue
+    }
+    svg.WriteString("\n")
+    child.element.write(svg, precision, indent + "  ")
+  }
+
+  if ! inline {
+    svg.WriteString("\n")
+    svg.WriteString(indent)
+  }
+  svg.WriteString("</")
+  svg.WriteString(element.Name)
+  svg.WriteString(">")
+}
+
+
+func (element * SvgElement) WriteTo (io_writer io.Writer) (int64, error) {
+  /*
+    Write the element and its descendants as they go, without building the
+    whole document first.
+  */
+  svg := svgWriter { writer: io_writer }
+  element.write(&svg, element.Precision, "")
+  return svg.written, svg.err
+}
+
+
+func (element * SvgElement) String () string {
+  var svg strings.Builder
+  element.WriteTo(&svg)
+  return svg.String()
+}
+
+
+//
+// Path data
+//
+
+
+type svgPathCommand struct {
+  command   byte;
+  arguments [] float64;
+}
+
+
+type SvgPath struct {
+  commands [] svgPathCommand;
+}
+
+
+func (path * SvgPath) add (command byte, arguments ...float64) * SvgPath {
+  path.commands = append(path.commands, svgPathCommand { command, arguments })
+  return path
+}
+
+
+func (path * SvgPath) MoveTo (x, y float64) * SvgPath {
+  return path.add('M', x, y)
+}
+
+
+func (path * SvgPath) LineTo (x, y float64) * SvgPath {
+  return path.add('L', x, y)
+}
+
+
+func (path * SvgPath) CurveTo (x1, y1, x2, y2, x, y float64) * SvgPath {
+  return path.add('C', x1, y1, x2, y2, x, y)
+}
+
+
+func (path * SvgPath) ArcTo (rx, ry, rotation float64, large_arc, sweep bool, x, y float64) * SvgPath {
+  large_arc_flag, sweep_flag := 0.0, 0.0
+  if large_arc { large_arc_flag = 1 }
+  if sweep     { sweep_flag     = 1 }
+  return path.add('A', rx, ry, rotation, large_arc_flag, sweep_flag, x, y)
+}
+
+
+func (path * SvgPath) Close () * SvgPath {
+  return path.add('Z')
+}
+
+
+func (path * SvgPath) Format (precision int) string {
+  var data strings.Builder
+  for command_i, command := range path.commands {
+    if command_i > 0 {
+      data.WriteString(" ")
+    }
+    data.WriteByte(command.command)
+    for _, argument := range command.arguments {
+      data.WriteString(" ")
+      data.WriteString(svgFormatNumber(argument, precision))
+    }
+  }
+  return data.String()
+}
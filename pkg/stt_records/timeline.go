@@ -0,0 +1,283 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "time"
+)
+
+
+type timelineBar struct {
+  row    int;
+  series string;
+  start  time.Time;
+  end    time.Time;
+  record * ActivityRecord;
+}
+
+
+func timelineBarTitle (record * ActivityRecord) string {
+  title := fmt.Sprintf(
+    "%s, %s–%s (%s)",
+    record.Activity_name, record.Time_started.Format("15:04"), record.Time_ended.Format("15:04"),
+    minutesFormatDuration(record.Duration_minutes),
+  )
+  if record.Comment != "" {
+    title += "\n" + record.Comment
+  }
+  return title
+}
+
+
+func activityRecordsTimelineSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot records on a horizontal time axis, from Time_started to Time_ended,
+    with their comments as hover titles. By default there's a lane per
+    activity (or category or tag, per the Group_by option) across the records'
+    whole time span; with Group_by "day", there's a row per day, from the
+    first record's day to the final record's, each across its 24 hours (see
+    DayStart), with records colored by activity.
+  */
+
+  options = svgChartOptions(options, "720", "")
+  by_day := options.Group_by == "day"
+
+  series_key := chartSeriesKey(options)
+  if by_day {
+    series_key = "activity"
+  }
+  series_groups, _ := ActivityRecordsAggregate(records, series_key)
+  AggregateGroupsSortBySum(series_groups)
+  series_indices := make(map [string] int, len(series_groups))
+  for series_i, series := range series_groups {
+    series_indices[series.Keys[0]] = series_i
+  }
+
+  // Rows, and the span of time across them. Each day's row starts
+  // STT_DAY_OFFSET after midnight; lanes span the records' hours.
+
+  var row_labels [] string
+  var bars       [] timelineBar
+  var span_start, span_end time.Time
+
+  if by_day {
+    day_groups, _ := ActivityRecordsAggregate(records, "day")
+    day_rows      := make(map [string] int)
+    if len(day_groups) > 0 {
+      first_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_groups[0].Keys[0], SttLocation())
+      final_day, _ := time.ParseInLocation(STT_DATE_LAYOUT, day_groups[len(day_groups)-1].Keys[0], SttLocation())
+      for day := first_day; ! day.After(final_day); day = day.AddDate(0, 0, 1) {
+        day_rows[day.Format(STT_DATE_LAYOUT)] = len(row_labels)
+        row_labels = append(row_labels, day.Format("Mon 2 Jan"))
+      }
+    }
+
+    for record_i := range records {
+      // Times within the row are from its day's start, on the zero time
+      record    := &records[record_i]
+      day       := record.DayStart()
+      row_start := day.Add(STT_DAY_OFFSET)
+      bars = append(bars, timelineBar {
+        row:    day_rows[day.Format(STT_DATE_LAYOUT)],
+        series: record.Activity_name,
+        start:  time.Time {}.Add(record.Time_started.Sub(row_start)),
+        end:    time.Time {}.Add(record.Time_ended.Sub(row_start)),
+        record: record,
+      })
+    }
+    span_start = time.Time {}
+    span_end   = span_start.Add(24 * time.Hour)
+
+  } else {
+    for _, series := range series_groups {
+      row_labels = append(row_labels, series.Keys[0])
+    }
+
+    for record_i := range records {
+      record := &records[record_i]
+      for _, value := range aggregateKeyValues(record, series_key) {
+        bars = append(bars, timelineBar {
+          row:    series_indices[value.value],
+          series: value.value,
+          start:  record.Time_started,
+          end:    record.Time_ended,
+          record: record,
+        })
+      }
+      if span_start.IsZero() || record.Time_started.Before(span_start) {
+        span_start = record.Time_started
+      }
+      if record.Time_ended.After(span_end) {
+        span_end = record.Time_ended
+      }
+    }
+
+    // Round the span out to hours on the clock, which in time zones offset
+    // by half hours aren't where Truncate would put them
+    hourStart := func (datetime time.Time) time.Time {
+      year, month, day := datetime.Date()
+      return time.Date(year, month, day, datetime.Hour(), 0, 0, 0, datetime.Location())
+    }
+    span_start = hourStart(span_start)
+    if ! span_end.Equal(hourStart(span_end)) {
+      span_end = hourStart(span_end).Add(time.Hour)
+    }
+    if ! span_end.After(span_start) {
+      span_end = span_start.Add(time.Hour)
+    }
+  }
+
+  // Geometry, in viewBox units: a label column, then the rows, then a row
+  // for the time axis labels
+
+  const label_width  float64 = 110
+  const plot_top     float64 = 10
+  const plot_width   float64 = 600
+  const row_height   float64 = 18
+  const bar_height   float64 = 12
+  const axis_height  float64 = 20
+
+  plot_height := float64(len(row_labels)) * row_height
+  legend_top  := plot_top + plot_height + axis_height
+  legend_rows := 0
+  if by_day {
+    legend_rows = len(series_groups)
+  }
+
+  span := span_end.Sub(span_start)
+  x := func (at time.Time) float64 {
+    return label_width + plot_width * math.Max(0, math.Min(1, float64(at.Sub(span_start)) / float64(span)))
+  }
+
+  title := "Timeline by " + series_key
+  if by_day {
+    title = "Timeline by day"
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, label_width + plot_width + 10, legend_top + float64(legend_rows) * 16 },
+    title, fmt.Sprintf("%d records, %s", len(records), chartDescribeRecords(records, series_groups)),
+    "    rect.lane { fill: #8881; }\n" +
+    "    rect.record:hover { stroke: #555; stroke-width: 1; }\n" +
+    "    line.grid { stroke: #8884; stroke-width: 0.5; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #666; }\n",
+  )
+
+  // Gridlines and labels every few hours, at most twelve of them
+
+  step := time.Hour
+  for _, hours := range [] int { 1, 2, 3, 6, 12, 24, 48, 168 } {
+    step = time.Duration(hours) * time.Hour
+    if span / step <= 12 { break }
+  }
+  label_layout := "15:04"
+  if ! by_day && span > 24 * time.Hour {
+    label_layout = "Mon 15:04"
+  }
+
+  for tick := span_start; ! tick.After(span_end); tick = tick.Add(step) {
+    label := tick.Format(label_layout)
+    if by_day {
+      label = tick.Add(STT_DAY_OFFSET).Format(label_layout)
+    }
+    svg.Add("line").Attr("class", "grid").
+      Num("x1", x(tick)).Num("y1", plot_top).Num("x2", x(tick)).Num("y2", plot_top + plot_height)
+    svg.Add("text").
+      Num("x", x(tick)).Num("y", plot_top + plot_height + 12).Attr("text-anchor", "middle").
+      Text(label)
+  }
+
+  // Rows, and their records
+
+  for row_i, label := range row_labels {
+    row_y := plot_top + float64(row_i) * row_height
+    svg.Add("text").
+      Num("x", label_width - 6).Num("y", row_y + row_height/2).
+      Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+      Text(label)
+    svg.Add("rect").Attr("class", "lane").
+      Num("x", label_width).Num("y", row_y + (row_height - bar_height)/2).Num("width", plot_width).Num("height", bar_height)
+  }
+
+  for _, bar := range bars {
+    bar_x := x(bar.start)
+    svg.Add("rect").Attr("class", "record").
+      Num("x", bar_x).Num("y", plot_top + float64(bar.row) * row_height + (row_height - bar_height)/2).
+      Num("width", math.Max(0.5, x(bar.end) - bar_x)).Num("height", bar_height).
+      Attr("fill", chartColor(options, bar.series)).
+      Title(timelineBarTitle(bar.record))
+  }
+
+  // Legend, for the activities' colors in the rows of days
+
+  for series_i := 0; series_i < legend_rows; series_i++ {
+    row_y := legend_top + float64(series_i) * 16
+    svg.Add("rect").
+      Num("x", label_width).Num("y", row_y).Num("width", 10).Num("height", 10).
+      Attr("fill", chartColor(options, series_groups[series_i].Keys[0]))
+    svg.Add("text").
+      Num("x", label_width + 14).Num("y", row_y + 5).Attr("dominant-baseline", "middle").
+      Text(series_groups[series_i].Keys[0])
+  }
+
+  return svg
+}
+
+
+func activityRecordsTimelineTable (records [] ActivityRecord, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per bar: per record in each lane, by start time, or with Group_by
+    "day", per record in each day's row.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+  by_day := options.Group_by == "day"
+  series_key := chartSeriesKey(options)
+
+  ordered := make([] * ActivityRecord, len(records))
+  for record_i := range records {
+    ordered[record_i] = &records[record_i]
+  }
+  sort.SliceStable(ordered, func (i, j int) bool {
+    return ordered[i].Time_started.Before(ordered[j].Time_started)
+  })
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Timeline by " + series_key),
+    Columns: [] string { chartTableHeading(series_key), "Activity", "Start", "End", "Duration", "Comment" },
+  }
+  addRow := func (heading string, record * ActivityRecord) {
+    table.Rows = append(table.Rows, [] string {
+      heading, record.Activity_name,
+      record.Time_started.Format("2006-01-02 15:04"), record.Time_ended.Format("2006-01-02 15:04"),
+      minutesFormatDuration(record.Duration_minutes), record.Comment,
+    })
+  }
+
+  if by_day {
+    table.Caption    = chartTitle(options, "Timeline by day")
+    table.Columns[0] = "Day"
+    for _, record := range ordered {
+      addRow(record.DayStart().Format(STT_DATE_LAYOUT), record)
+    }
+    return table
+  }
+
+  series_groups, _ := ActivityRecordsAggregate(records, series_key)
+  AggregateGroupsSortBySum(series_groups)
+  for _, series := range series_groups {
+    for _, record := range ordered {
+      for _, value := range aggregateKeyValues(record, series_key) {
+        if value.value == series.Keys[0] {
+          addRow(series.Keys[0], record)
+        }
+      }
+    }
+  }
+  return table
+}
@@ -0,0 +1,210 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "io"
+  "math"
+  "sort"
+  "time"
+)
+
+
+const TIMESHEET_UNTAGGED string = "(untagged)"
+
+
+type TimesheetOptions struct {
+  /*
+    Group_by is "activity" (the default) or "tag". Each day's total for an
+    activity or tag is rounded to a multiple of Round_to, if it's non-zero,
+    either to the "nearest" multiple (the default), "up" or "down".
+  */
+  Group_by   string;
+  Round_to   time.Duration;
+  Round_mode string;
+}
+
+
+func (options *TimesheetOptions) Validate () error {
+  switch options.Group_by {
+  case "", "activity", "tag":
+  default:
+    return fmt.Errorf("unknown timesheet grouping \"%s\", expected activity or tag", options.Group_by)
+  }
+
+  switch options.Round_mode {
+  case "", "nearest", "up", "down":
+  default:
+    return fmt.Errorf("unknown rounding mode \"%s\", expected nearest, up or down", options.Round_mode)
+  }
+
+  if options.Round_to < 0 {
+    return fmt.Errorf("rounding must not be negative")
+  }
+
+  return nil
+}
+
+
+func (options *TimesheetOptions) round (duration time.Duration) time.Duration {
+  if options.Round_to <= 0 { return duration }
+
+  increments := float64(duration) / float64(options.Round_to)
+  switch options.Round_mode {
+  case "up":
+    increments = math.Ceil(increments)
+  case "down":
+    increments = math.Floor(increments)
+  default:
+    increments = math.Round(increments)
+  }
+  return time.Duration(increments) * options.Round_to
+}
+
+
+func timesheetKeys (record * ActivityRecord, group_by string) [] string {
+  if group_by != "tag" {
+    return [] string { record.Activity_name }
+  }
+  tags := record.Tags()
+  if len(tags) == 0 {
+    return [] string { TIMESHEET_UNTAGGED }
+  }
+  return tags
+}
+
+
+func ActivityRecordsTimesheet (records [] ActivityRecord, options * TimesheetOptions) (workbook XlsxWorkbook, err error) {
+  /*
+    Build a timesheet workbook with one sheet per week (starting on
+    STT_WEEK_START, and named by ISO week number): a row per day and
+    activity (or tag) with its hours, day and week totals, and each activity's
+    total for the week. When grouping by tag, a record with several tags counts
+    toward each of them.
+  */
+
+  if options == nil {
+    options = & TimesheetOptions {}
+  }
+  if err = options.Validate(); err != nil { return }
+
+  group_label := "Activity"
+  if options.Group_by == "tag" {
+    group_label = "Tag"
+  }
+
+  // Lay out a sheet per week, summing durations by day, then activity or
+  // tag. Totals are sums of the rounded hours, so that they agree with the
+  // rows above them.
+
+  for _, week := range ActivityRecordsByWeek(records) {
+    days := make(map [time.Time] map [string] time.Duration)
+    for record_i := range week.Records {
+      record := &week.Records[record_i]
+      day    := record.DayStart()
+      if days[day] == nil {
+        days[day] = make(map [string] time.Duration)
+      }
+      for _, key := range timesheetKeys(record, options.Group_by) {
+        days[day][key] += record.Duration
+      }
+    }
+
+    week_start := week.Start
+    sheet      := workbook.AddSheet(WeekLabel(week_start))
+    sheet.Column_widths = [] float64 { 12, 12, 28, 10 }
+
+    sheet.AddRow(XlsxText(fmt.Sprintf(
+      "Week %s: %s – %s",
+      WeekLabel(week_start), week_start.Format(STT_DATE_LAYOUT), week.End().Format(STT_DATE_LAYOUT),
+    ), XLSX_STYLE_BOLD))
+    sheet.AddRow()
+    sheet.AddRow(
+      XlsxText("Date",      XLSX_STYLE_BOLD),
+      XlsxText("Day",       XLSX_STYLE_BOLD),
+      XlsxText(group_label, XLSX_STYLE_BOLD),
+      XlsxText("Hours",     XLSX_STYLE_BOLD),
+    )
+
+    week_hours := 0.0
+    key_hours  := make(map [string] float64)
+
+    for day_i := 0; day_i < 7; day_i++ {
+      day       := week_start.AddDate(0, 0, day_i)
+      day_keys  := days[day]
+      if len(day_keys) == 0 { continue }
+
+      keys := make([] string, 0, len(day_keys))
+      for key := range day_keys {
+        keys = append(keys, key)
+      }
+      sort.Strings(keys)
+
+      day_hours := 0.0
+      for _, key := range keys {
+        hours := options.round(day_keys[key]).Hours()
+        day_hours      += hours
+        key_hours[key] += hours
+        sheet.AddRow(
+          XlsxText(day.Format(STT_DATE_LAYOUT), XLSX_STYLE_DEFAULT),
+          XlsxText(day.Weekday().String(), XLSX_STYLE_DEFAULT),
+          XlsxText(key, XLSX_STYLE_DEFAULT),
+          XlsxNumber(hours, XLSX_STYLE_HOURS),
+        )
+      }
+
+      sheet.AddRow(
+        XlsxText("", XLSX_STYLE_DEFAULT),
+        XlsxText("", XLSX_STYLE_DEFAULT),
+        XlsxText("Day total", XLSX_STYLE_BOLD),
+        XlsxNumber(day_hours, XLSX_STYLE_HOURS_BOLD),
+      )
+      week_hours += day_hours
+    }
+
+    sheet.AddRow(
+      XlsxText("Week total", XLSX_STYLE_BOLD),
+      XlsxText("", XLSX_STYLE_DEFAULT),
+      XlsxText("", XLSX_STYLE_DEFAULT),
+      XlsxNumber(week_hours, XLSX_STYLE_HOURS_BOLD),
+    )
+
+    // Week totals per activity or tag, largest first
+
+    keys := make([] string, 0, len(key_hours))
+    for key := range key_hours {
+      keys = append(keys, key)
+    }
+    sort.Slice(keys, func (i, j int) bool {
+      if key_hours[keys[i]] != key_hours[keys[j]] {
+        return key_hours[keys[i]] > key_hours[keys[j]]
+      }
+      return keys[i] < keys[j]
+    })
+
+    sheet.AddRow()
+    sheet.AddRow(
+      XlsxText(group_label, XLSX_STYLE_BOLD),
+      XlsxText("",          XLSX_STYLE_DEFAULT),
+      XlsxText("",          XLSX_STYLE_DEFAULT),
+      XlsxText("Hours",     XLSX_STYLE_BOLD),
+    )
+    for _, key := range keys {
+      sheet.AddRow(
+        XlsxText(key, XLSX_STYLE_DEFAULT),
+        XlsxText("",  XLSX_STYLE_DEFAULT),
+        XlsxText("",  XLSX_STYLE_DEFAULT),
+        XlsxNumber(key_hours[key], XLSX_STYLE_HOURS),
+      )
+    }
+  }
+
+  return workbook, nil
+}
+
+
+func ActivityRecordsWriteTimesheet (io_writer io.Writer, records [] ActivityRecord, options * TimesheetOptions) error {
+  workbook, err := ActivityRecordsTimesheet(records, options)
+  if err != nil { return err }
+  return workbook.Write(io_writer)
+}
@@ -0,0 +1,291 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+  "sort"
+  "strings"
+  "time"
+)
+
+
+const TRANSITIONS_MAX_GAP time.Duration = 30 * time.Minute
+
+
+type ActivityTransitions struct {
+  /*
+    How often each activity followed each other one. Counts[from][to] indexes
+    Activities, which are ordered by how many transitions they're in, most
+    first.
+  */
+  Activities [] string;
+  Counts     [][] int;
+  Total      int;
+}
+
+
+func ActivityRecordsTransitions (records [] ActivityRecord, max_gap time.Duration) (transitions ActivityTransitions) {
+  /*
+    Count the transitions from each record to the next one to start, unless
+    there's more than max_gap between the first's end and the second's start.
+  */
+
+  ordered := make([] ActivityRecord, len(records))
+  copy(ordered, records)
+  sort.SliceStable(ordered, func (i, j int) bool {
+    return ordered[i].Time_started.Before(ordered[j].Time_started)
+  })
+
+  type transition struct { from, to string }
+  counts         := make(map [transition] int)
+  activity_counts := make(map [string] int)
+
+  for record_i := 1; record_i < len(ordered); record_i++ {
+    from := &ordered[record_i - 1]
+    to   := &ordered[record_i]
+    if to.Time_started.Sub(from.Time_ended) > max_gap { continue }
+
+    counts[transition { from.Activity_name, to.Activity_name }]++
+    activity_counts[from.Activity_name]++
+    activity_counts[to.Activity_name]++
+    transitions.Total++
+  }
+
+  for activity := range activity_counts {
+    transitions.Activities = append(transitions.Activities, activity)
+  }
+  sort.Slice(transitions.Activities, func (i, j int) bool {
+    activity_i, activity_j := transitions.Activities[i], transitions.Activities[j]
+    if activity_counts[activity_i] != activity_counts[activity_j] {
+      return activity_counts[activity_i] > activity_counts[activity_j]
+    }
+    return activity_i < activity_j
+  })
+
+  transitions.Counts = make([][] int, len(transitions.Activities))
+  for from_i, from := range transitions.Activities {
+    transitions.Counts[from_i] = make([] int, len(transitions.Activities))
+    for to_i, to := range transitions.Activities {
+      transitions.Counts[from_i][to_i] = counts[transition { from, to }]
+    }
+  }
+
+  return transitions
+}
+
+
+func (transitions * ActivityTransitions) From (activity_i int) (count int) {
+  for _, to_count := range transitions.Counts[activity_i] {
+    count += to_count
+  }
+  return count
+}
+
+
+func (transitions * ActivityTransitions) To (activity_i int) (count int) {
+  for from_i := range transitions.Counts {
+    count += transitions.Counts[from_i][activity_i]
+  }
+  return count
+}
+
+
+func activityTransitionsDescribe (transitions * ActivityTransitions) string {
+  /*
+    A description of the transitions, naming the most common ones.
+  */
+
+  if transitions.Total == 0 {
+    return "No transitions."
+  }
+
+  type pair struct { from, to, count int }
+  var pairs [] pair
+  for from_i, counts := range transitions.Counts {
+    for to_i, count := range counts {
+      if count > 0 {
+        pairs = append(pairs, pair { from_i, to_i, count })
+      }
+    }
+  }
+  sort.SliceStable(pairs, func (i, j int) bool { return pairs[i].count > pairs[j].count })
+
+  descriptions := make([] string, 0, CHART_DESCRIBE_MAX)
+  for _, pair := range pairs {
+    if len(descriptions) == CHART_DESCRIBE_MAX { break }
+    times := "times"
+    if pair.count == 1 {
+      times = "time"
+    }
+    descriptions = append(descriptions, fmt.Sprintf(
+      "%s to %s %d %s (%.1f%%)",
+      transitions.Activities[pair.from], transitions.Activities[pair.to], pair.count, times,
+      100 * float64(pair.count) / float64(transitions.Total),
+    ))
+  }
+  return fmt.Sprintf(
+    "%d transitions between %d activities. The most common are %s.",
+    transitions.Total, len(transitions.Activities), strings.Join(descriptions, ", "),
+  )
+}
+
+
+func activityTransitionsSankeySvg (transitions * ActivityTransitions, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot transitions as a Sankey diagram: each activity as a node on the left,
+    sized by the transitions from it, and on the right, sized by those to it,
+    with a band from left to right for each pair of activities, as wide as
+    their transitions.
+  */
+
+  options = svgChartOptions(options, "600", "400")
+
+  // Geometry, in viewBox units: labels either side of the node columns
+
+  const label_width  float64 = 110
+  const node_width   float64 = 12
+  const flow_width   float64 = 300
+  const plot_top     float64 = 10
+  const plot_height  float64 = 380
+
+  left_x  := label_width
+  right_x := label_width + node_width + flow_width
+
+  // Gaps between nodes take at most half the column, however many activities
+  // there are, leaving at least the other half for the transitions
+
+  activity_count := len(transitions.Activities)
+  node_gap := 6.0
+  if activity_count > 1 {
+    node_gap = math.Min(node_gap, plot_height / 2 / float64(activity_count - 1))
+  }
+  scale := 0.0
+  if transitions.Total > 0 {
+    scale = (plot_height - node_gap * float64(activity_count - 1)) / float64(transitions.Total)
+    scale = math.Max(scale, plot_height / 2 / float64(transitions.Total))
+  }
+
+  // Nodes' tops, stacked down each column, skipping activities without any
+  // transitions on that side
+
+  left_y  := make([] float64, activity_count)
+  right_y := make([] float64, activity_count)
+  next_left_y, next_right_y := plot_top, plot_top
+  for activity_i := range transitions.Activities {
+    left_y[activity_i]  = next_left_y
+    right_y[activity_i] = next_right_y
+    if from := transitions.From(activity_i); from > 0 {
+      next_left_y += float64(from) * scale + node_gap
+    }
+    if to := transitions.To(activity_i); to > 0 {
+      next_right_y += float64(to) * scale + node_gap
+    }
+  }
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, 2 * label_width + 2 * node_width + flow_width, plot_top + plot_height + 10 },
+    "Transitions between activities", activityTransitionsDescribe(transitions),
+    "    path.flow { fill-opacity: 0.45; }\n" +
+    "    path.flow:hover { fill-opacity: 0.8; }\n" +
+    "    rect.node:hover { stroke: #555; stroke-width: 0.5; }\n" +
+    "    text { font-family: sans-serif; font-size: 9px; fill: #444; }\n",
+  )
+
+  // Bands, each from its place in the source's node to its place in the
+  // target's, in order down both columns
+
+  from_offsets := make([] float64, activity_count)
+  to_offsets   := make([] float64, activity_count)
+  flow_left_x  := left_x + node_width
+  flow_mid_x   := flow_left_x + flow_width / 2
+
+  for from_i, from := range transitions.Activities {
+    from_count := transitions.From(from_i)
+    for to_i, to := range transitions.Activities {
+      count := transitions.Counts[from_i][to_i]
+      if count == 0 { continue }
+
+      band := float64(count) * scale
+      y0   := left_y[from_i] + from_offsets[from_i]
+      y1   := right_y[to_i] + to_offsets[to_i]
+      from_offsets[from_i] += band
+      to_offsets[to_i]     += band
+
+      path := & SvgPath {}
+      path.MoveTo(flow_left_x, y0).
+        CurveTo(flow_mid_x, y0, flow_mid_x, y1, right_x, y1).
+        LineTo(right_x, y1 + band).
+        CurveTo(flow_mid_x, y1 + band, flow_mid_x, y0 + band, flow_left_x, y0 + band).
+        Close()
+
+      svg.Add("path").Attr("class", "flow").
+        Path(path).
+        Attr("fill", chartColor(options, from)).
+        Title(fmt.Sprintf(
+          "%s → %s: %d of %s's %d transitions (%.1f%%)",
+          from, to, count, from, from_count, 100 * float64(count) / float64(from_count),
+        ))
+    }
+  }
+
+  // Nodes and their labels
+
+  for activity_i, activity := range transitions.Activities {
+    fill := chartColor(options, activity)
+
+    if from := transitions.From(activity_i); from > 0 {
+      height := math.Max(1, float64(from) * scale)
+      svg.Add("rect").Attr("class", "node").
+        Num("x", left_x).Num("y", left_y[activity_i]).Num("width", node_width).Num("height", height).
+        Attr("fill", fill).
+        Title(fmt.Sprintf("%s: %d transitions out", activity, from))
+      svg.Add("text").
+        Num("x", left_x - 4).Num("y", left_y[activity_i] + height/2).
+        Attr("text-anchor", "end").Attr("dominant-baseline", "middle").
+        Text(activity)
+    }
+
+    if to := transitions.To(activity_i); to > 0 {
+      height := math.Max(1, float64(to) * scale)
+      svg.Add("rect").Attr("class", "node").
+        Num("x", right_x).Num("y", right_y[activity_i]).Num("width", node_width).Num("height", height).
+        Attr("fill", fill).
+        Title(fmt.Sprintf("%s: %d transitions in", activity, to))
+      svg.Add("text").
+        Num("x", right_x + node_width + 4).Num("y", right_y[activity_i] + height/2).Attr("dominant-baseline", "middle").
+        Text(activity)
+    }
+  }
+
+  return svg
+}
+
+
+func activityTransitionsTable (transitions * ActivityTransitions, options * ActivityRecordChartOptions) * ChartTable {
+  /*
+    A row per flow, from each activity to each one that followed it, with
+    how often it did and its share of all the transitions.
+  */
+
+  if options == nil {
+    options = & ActivityRecordChartOptions {}
+  }
+
+  table := & ChartTable {
+    Caption: chartTitle(options, "Transitions between activities"),
+    Columns: [] string { "From", "To", "Transitions", "Share" },
+    Footer:  [] string { "Total", "", fmt.Sprint(transitions.Total), "" },
+  }
+  for from_i, counts := range transitions.Counts {
+    for to_i, count := range counts {
+      if count == 0 { continue }
+      table.Rows = append(table.Rows, [] string {
+        transitions.Activities[from_i], transitions.Activities[to_i],
+        fmt.Sprint(count), chartTablePercent(100 * float64(count) / float64(transitions.Total)),
+      })
+    }
+  }
+  return table
+}
@@ -0,0 +1,49 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "testing"
+  "time"
+)
+
+
+func TestActivityTransitionsPlotSankeyFits (t * testing.T) {
+  // However many activities there are, the nodes and bands stay in the
+  // viewBox, 400 high, with positive heights and thicknesses
+  for _, activity_count := range [] int { 0, 1, 2, 10, 64, 200 } {
+    start   := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+    records := make([] ActivityRecord, activity_count)
+    for record_i := range records {
+      record_start := start.Add(time.Duration(record_i) * 10 * time.Minute).Format("2006-01-02 15:04")
+      record_end   := start.Add(time.Duration(record_i + 1) * 10 * time.Minute).Format("2006-01-02 15:04")
+      records[record_i] = testRecord(fmt.Sprintf("Activity %d", record_i), record_start, record_end)
+    }
+
+    svg := testChartSvg(t, "transitions", records, nil)
+
+    for _, match := range regexp.MustCompile(` (y|height)="([^"]*)"`).FindAllStringSubmatch(svg, -1) {
+      number, err := strconv.ParseFloat(match[2], 64)
+      if err != nil {
+        t.Fatalf("%d activities: %s=%q", activity_count, match[1], match[2])
+      }
+      if number < 0 || number > 400 || (match[1] == "height" && number == 0) {
+        t.Errorf("%d activities: %s=%q", activity_count, match[1], match[2])
+      }
+    }
+
+    // A band's path goes along its top to the target node, and back along its
+    // bottom: M x y0 C x y0, x y1, x y1 L x y1+band ...
+    for _, match := range regexp.MustCompile(`class="flow" d="([^"]*)"`).FindAllStringSubmatch(svg, -1) {
+      numbers := regexp.MustCompile(`-?[0-9.]+`).FindAllString(match[1], -1)
+      y1, _   := strconv.ParseFloat(numbers[7], 64)
+      y1b, _  := strconv.ParseFloat(numbers[9], 64)
+      if !(y1b > y1) || y1 < 0 || y1b > 400 {
+        t.Errorf("%d activities: band from %g to %g in %q", activity_count, y1, y1b, match[1])
+        break
+      }
+    }
+  }
+}
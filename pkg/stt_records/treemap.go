@@ -0,0 +1,194 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "math"
+)
+
+
+type chartRect struct {
+  x, y, width, height float64;
+}
+
+
+func chartSquarifyWorst (row [] float64, side float64) float64 {
+  /*
+    The worst aspect ratio among rectangles of the given areas, laid in a row
+    along a side of the given length.
+  */
+  sum, min_area, max_area := 0.0, math.Inf(1), 0.0
+  for _, area := range row {
+    sum     += area
+    min_area = math.Min(min_area, area)
+    max_area = math.Max(max_area, area)
+  }
+  return math.Max(side * side * max_area / (sum * sum), sum * sum / (side * side * min_area))
+}
+
+
+func chartSquarify (values [] float64, bounds chartRect) [] chartRect {
+  /*
+    Divide bounds into a rectangle per value, in proportion to it, keeping
+    them as close to square as it can (per Bruls, Huizing and van Wijk's
+    squarified treemap algorithm). Values should be largest first; those that
+    aren't positive get an empty rectangle at bounds' corner.
+  */
+
+  rects := make([] chartRect, len(values))
+  total := 0.0
+  for value_i, value := range values {
+    rects[value_i] = chartRect { bounds.x, bounds.y, 0, 0 }
+    if value > 0 { total += value }
+  }
+  if total <= 0 || bounds.width <= 0 || bounds.height <= 0 { return rects }
+
+  // Lay out only the positive values, as a row of nothing has no thickness
+  // to divide by
+  scale   := bounds.width * bounds.height / total
+  areas   := make([] float64, 0, len(values))
+  indices := make([] int, 0, len(values))
+  for value_i, value := range values {
+    if value <= 0 { continue }
+    areas   = append(areas, value * scale)
+    indices = append(indices, value_i)
+  }
+
+  // Fill rows along the shorter side of what's left of the bounds, adding
+  // rectangles to a row while that doesn't make its worst one worse
+
+  remaining := bounds
+  for row_start := 0; row_start < len(areas); {
+    side    := math.Min(remaining.width, remaining.height)
+    row_end := row_start + 1
+    for row_end < len(areas) &&
+        chartSquarifyWorst(areas[row_start:row_end+1], side) <= chartSquarifyWorst(areas[row_start:row_end], side) {
+      row_end++
+    }
+
+    row_area := 0.0
+    for _, area := range areas[row_start:row_end] {
+      row_area += area
+    }
+    thickness := row_area / side
+    offset    := 0.0
+
+    for area_i := row_start; area_i < row_end; area_i++ {
+      length := areas[area_i] / thickness
+      if remaining.width >= remaining.height {
+        rects[indices[area_i]] = chartRect { remaining.x, remaining.y + offset, thickness, length }
+      } else {
+        rects[indices[area_i]] = chartRect { remaining.x + offset, remaining.y, length, thickness }
+      }
+      offset += length
+    }
+
+    if remaining.width >= remaining.height {
+      remaining.x     += thickness
+      remaining.width -= thickness
+    } else {
+      remaining.y      += thickness
+      remaining.height -= thickness
+    }
+    row_start = row_end
+  }
+
+  return rects
+}
+
+
+func activityRecordsTreemapSvg (records [] ActivityRecord, options * ActivityRecordChartOptions) * SvgElement {
+  /*
+    Plot a squarified treemap of categories, each divided among its
+    activities, with areas in proportion to their minutes. Categories large
+    enough have a header with their name and total, and activities large
+    enough are labelled.
+  */
+
+  options = svgChartOptions(options, "600", "360")
+  branches, total_minutes := activityRecordsHierarchy(records)
+
+  // Geometry, in viewBox units
+
+  const width         float64 = 600
+  const height        float64 = 360
+  const header_height float64 = 13
+  const padding       float64 = 2
+
+  category_groups, _ := ActivityRecordsAggregate(records, "category")
+
+  svg := svgChartRoot(
+    options,
+    [4] float64 { 0, 0, width, height },
+    "Time by category and activity", chartDescribeRecords(records, category_groups),
+    "    rect.category { stroke: #fff; stroke-width: 2; }\n" +
+    "    rect.activity { stroke: #fff; stroke-width: 0.75; }\n" +
+    "    rect.activity:hover { filter: brightness(1.1); stroke: #555; }\n" +
+    "    text { font-family: sans-serif; font-size: 8px; pointer-events: none; }\n" +
+    "    text.category { font-weight: bold; }\n",
+  )
+
+  branch_values := make([] float64, len(branches))
+  for branch_i, branch := range branches {
+    branch_values[branch_i] = float64(branch.minutes)
+  }
+  branch_rects := chartSquarify(branch_values, chartRect { 0, 0, width, height })
+
+  // Each category's rectangle, with a header if there's room for one, and
+  // its activities' rectangles in the rest
+
+  for branch_i, branch := range branches {
+    rect := branch_rects[branch_i]
+    fill := chartColor(options, branch.name)
+    if rect.width <= 0 || rect.height <= 0 { continue }
+
+    svg.Add("rect").Attr("class", "category").
+      Num("x", rect.x).Num("y", rect.y).Num("width", rect.width).Num("height", rect.height).
+      Attr("fill", fill).
+      Title(fmt.Sprintf(
+        "%s: %s (%.1f%%)",
+        branch.name, minutesFormatDuration(branch.minutes), chartPercent(branch.minutes, total_minutes),
+      ))
+
+    inner := chartRect { rect.x + padding, rect.y + padding, rect.width - 2 * padding, rect.height - 2 * padding }
+    if rect.height > 3 * header_height && rect.width > 60 {
+      svg.Add("text").Attr("class", "category").
+        Num("x", inner.x + 2).Num("y", inner.y + header_height - 4).Attr("fill", ColorContrastText(fill)).
+        Text(branch.name + " " + minutesFormatDuration(branch.minutes))
+      inner.y      += header_height
+      inner.height -= header_height
+    }
+
+    child_values := make([] float64, len(branch.children))
+    for child_i, child := range branch.children {
+      child_values[child_i] = float64(child.Minutes)
+    }
+
+    for child_i, child_rect := range chartSquarify(child_values, inner) {
+      child      := branch.children[child_i]
+      child_fill := chartColor(options, child.Keys[1])
+      if child_rect.width <= 0 || child_rect.height <= 0 { continue }
+
+      svg.Add("rect").Attr("class", "activity").
+        Num("x", child_rect.x).Num("y", child_rect.y).Num("width", child_rect.width).Num("height", child_rect.height).
+        Attr("fill", child_fill).
+        Title(fmt.Sprintf(
+          "%s › %s: %s (%.1f%% of %s, %.1f%% overall)",
+          branch.name, child.Keys[1], minutesFormatDuration(child.Minutes),
+          chartPercent(child.Minutes, branch.minutes), branch.name,
+          chartPercent(child.Minutes, total_minutes),
+        ))
+
+      if child_rect.width > 40 && child_rect.height > 22 {
+        svg.Add("text").
+          Num("x", child_rect.x + 3).Num("y", child_rect.y + 10).Attr("fill", ColorContrastText(child_fill)).
+          Text(child.Keys[1])
+        svg.Add("text").
+          Num("x", child_rect.x + 3).Num("y", child_rect.y + 19).Attr("fill", ColorContrastText(child_fill)).
+          Text(minutesFormatDuration(child.Minutes))
+      }
+    }
+  }
+
+  return svg
+}
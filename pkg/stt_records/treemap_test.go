@@ -0,0 +1,78 @@
+package stt_records;
+
+
+import (
+  "math"
+  "testing"
+)
+
+
+func TestChartSquarify (t * testing.T) {
+  bounds := chartRect { 10, 20, 600, 400 }
+
+  tests := [] struct {
+    name   string;
+    values [] float64;
+  } {
+    { "none",            [] float64 {} },
+    { "one",             [] float64 { 5 } },
+    { "Bruls et al.",    [] float64 { 6, 6, 4, 3, 2, 2, 1 } },
+    { "one large",       [] float64 { 1000, 1, 1, 1 } },
+    { "equal",           [] float64 { 1, 1, 1, 1, 1, 1, 1, 1, 1 } },
+    { "trailing zeroes", [] float64 { 3, 2, 0, 0 } },
+    { "all zero",        [] float64 { 0, 0 } },
+  }
+
+  for _, test := range tests {
+    rects := chartSquarify(test.values, bounds)
+    if len(rects) != len(test.values) {
+      t.Errorf("%s: %d rectangles for %d values", test.name, len(rects), len(test.values))
+      continue
+    }
+
+    total := 0.0
+    for _, value := range test.values {
+      total += value
+    }
+
+    for rect_i, rect := range rects {
+      for _, number := range [] float64 { rect.x, rect.y, rect.width, rect.height } {
+        if math.IsNaN(number) || math.IsInf(number, 0) {
+          t.Fatalf("%s, value %d: %+v", test.name, rect_i, rect)
+        }
+      }
+
+      // Each rectangle is in the bounds, and its share of them
+      const epsilon = 1e-6
+      if rect.x < bounds.x - epsilon || rect.y < bounds.y - epsilon ||
+         rect.x + rect.width  > bounds.x + bounds.width  + epsilon ||
+         rect.y + rect.height > bounds.y + bounds.height + epsilon {
+        t.Errorf("%s, value %d: %+v is outside %+v", test.name, rect_i, rect, bounds)
+      }
+      want_area := 0.0
+      if total > 0 {
+        want_area = test.values[rect_i] / total * bounds.width * bounds.height
+      }
+      if math.Abs(rect.width * rect.height - want_area) > 1e-6 * bounds.width * bounds.height {
+        t.Errorf("%s, value %d: area %g, want %g", test.name, rect_i, rect.width * rect.height, want_area)
+      }
+    }
+  }
+}
+
+
+func TestChartsZeroMinutes (t * testing.T) {
+  // Records that start and end together have categories and activities but
+  // no time to divide among them
+  records := [] ActivityRecord {
+    testRecord("Email",  "2026-03-02 09:00", "2026-03-02 09:00"),
+    testRecord("Coding", "2026-03-02 10:00", "2026-03-02 10:30"),
+  }
+  records[0].Categories = [] string { "Communication" }
+  records[1].Categories = [] string { "Development" }
+
+  for _, name := range [] string { "treemap", "sunburst" } {
+    testChartSvg(t, name, records[:1], nil)
+    testChartSvg(t, name, records, nil)
+  }
+}
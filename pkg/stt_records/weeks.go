@@ -0,0 +1,130 @@
+package stt_records;
+
+
+import (
+  "fmt"
+  "sort"
+  "strings"
+  "time"
+)
+
+
+func ParseWeekday (weekday_str string) (weekday time.Weekday, err error) {
+  for weekday = time.Sunday; weekday <= time.Saturday; weekday++ {
+    name := strings.ToLower(weekday.String())
+    if strings.EqualFold(weekday_str, name) || strings.EqualFold(weekday_str, name[:3]) {
+      return weekday, nil
+    }
+  }
+  return time.Sunday, fmt.Errorf("invalid weekday \"%s\"", weekday_str)
+}
+
+
+func WeekStart (day time.Time) time.Time {
+  /*
+    The first day, per STT_WEEK_START, of the week a day (as returned by
+    DayStart) is in.
+  */
+  return day.AddDate(0, 0, -WeekdayIndex(day.Weekday()))
+}
+
+
+func ISOWeekStart (year, week int) (time.Time, error) {
+  /*
+    The Monday starting an ISO 8601 week. Week 1 is the week with the year's
+    first Thursday.
+  */
+
+  jan_4      := dayDate(year, time.January, 4)
+  week_1     := jan_4.AddDate(0, 0, -((int(jan_4.Weekday()) + 6) % 7))
+  week_start := week_1.AddDate(0, 0, 7 * (week - 1))
+
+  if week_year, _ := week_start.ISOWeek(); week < 1 || week_year != year {
+    return week_start, fmt.Errorf("%d has no ISO week %d", year, week)
+  }
+
+  return week_start, nil
+}
+
+
+func WeekThursday (week_start time.Time) time.Time {
+  /*
+    The Thursday within the 7 days from week_start. Every week, whichever day
+    it starts on, has exactly one, and ISO 8601 numbers weeks by it.
+  */
+  return week_start.AddDate(0, 0, (int(time.Thursday) - int(week_start.Weekday()) + 7) % 7)
+}
+
+
+func WeekISO (week_start time.Time) (year, week int) {
+  /*
+    The ISO 8601 year and week number of the week starting on week_start. For
+    weeks starting on Monday, these are exactly ISO weeks; otherwise, the ISO
+    week sharing the week's Thursday.
+  */
+  return WeekThursday(week_start).ISOWeek()
+}
+
+
+func WeekLabel (week_start time.Time) string {
+  year, week := WeekISO(week_start)
+  return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+
+func WeekOfISO (year, week int) (week_start time.Time, err error) {
+  /*
+    The start, per STT_WEEK_START, of the week labelled with the ISO year and
+    week number.
+  */
+  iso_start, err := ISOWeekStart(year, week)
+  if err != nil { return iso_start, err }
+  return WeekStart(iso_start.AddDate(0, 0, 3)), nil
+}
+
+
+func WeekdayIndex (weekday time.Weekday) int {
+  /*
+    A weekday's position in the week per STT_WEEK_START, from 0 to 6.
+  */
+  return (int(weekday) - int(STT_WEEK_START) + 7) % 7
+}
+
+
+type WeekRecords struct {
+  Start   time.Time;
+  Records [] ActivityRecord;
+}
+
+
+func (week *WeekRecords) End () time.Time {
+  /*
+    The last day of the week.
+  */
+  return week.Start.AddDate(0, 0, 6)
+}
+
+
+func ActivityRecordsByWeek (records [] ActivityRecord) [] WeekRecords {
+  /*
+    Bucket records into weeks, by their day. Weeks are in chronological order,
+    and weeks without records are left out.
+  */
+
+  weeks_by_start := make(map [time.Time] int)
+  weeks          := make([] WeekRecords, 0)
+
+  for _, record := range records {
+    week_start    := WeekStart(record.DayStart())
+    week_i, found := weeks_by_start[week_start]
+    if ! found {
+      week_i = len(weeks)
+      weeks_by_start[week_start] = week_i
+      weeks = append(weeks, WeekRecords { Start: week_start })
+    }
+    weeks[week_i].Records = append(weeks[week_i].Records, record)
+  }
+
+  sort.Slice(weeks, func (i, j int) bool { return weeks[i].Start.Before(weeks[j].Start) })
+  return weeks
+}
@@ -0,0 +1,268 @@
+package stt_records;
+
+
+import (
+  "archive/zip"
+  "encoding/xml"
+  "fmt"
+  "io"
+  "strconv"
+  "strings"
+)
+
+
+//
+// A minimal Office Open XML spreadsheet writer: enough of the format for
+// sheets of text and number cells, with a few fixed cell styles.
+//
+
+
+type XlsxStyle int
+
+const (
+  XLSX_STYLE_DEFAULT     XlsxStyle = 0
+  XLSX_STYLE_BOLD        XlsxStyle = 1
+  XLSX_STYLE_HOURS       XlsxStyle = 2
+  XLSX_STYLE_HOURS_BOLD  XlsxStyle = 3
+)
+
+
+type XlsxCell struct {
+  /*
+    A cell holds text, unless Is_number is set, in which case it holds Number.
+  */
+  Text      string;
+  Number    float64;
+  Is_number bool;
+  Style     XlsxStyle;
+}
+
+
+type XlsxSheet struct {
+  Name          string;
+  Rows          [][] XlsxCell;
+  Column_widths [] float64;
+}
+
+
+type XlsxWorkbook struct {
+  Sheets [] XlsxSheet;
+}
+
+
+func XlsxText (text string, style XlsxStyle) XlsxCell {
+  return XlsxCell { Text: text, Style: style }
+}
+
+
+func XlsxNumber (number float64, style XlsxStyle) XlsxCell {
+  return XlsxCell { Number: number, Is_number: true, Style: style }
+}
+
+
+func (workbook *XlsxWorkbook) AddSheet (name string) * XlsxSheet {
+  workbook.Sheets = append(workbook.Sheets, XlsxSheet { Name: name })
+  return &workbook.Sheets[len(workbook.Sheets)-1]
+}
+
+
+func (sheet *XlsxSheet) AddRow (cells ...XlsxCell) {
+  sheet.Rows = append(sheet.Rows, cells)
+}
+
+
+func xlsxEscape (text string) string {
+  var builder strings.Builder
+  xml.EscapeText(&builder, [] byte(text))
+  return builder.String()
+}
+
+
+func xlsxColumnName (column_i int) string {
+  /*
+    Zero-based column index to its letters: 0 is A, 25 is Z, 26 is AA.
+  */
+  name := ""
+  for column_i += 1; column_i > 0; column_i = (column_i - 1) / 26 {
+    name = string(rune('A' + (column_i - 1) % 26)) + name
+  }
+  return name
+}
+
+
+func xlsxSheetName (name string, used map [string] bool) string {
+  /*
+    Sheet names are at most 31 characters, can't contain any of []:*?/\ and
+    must be unique within a workbook.
+  */
+
+  name = strings.Map(func (r rune) rune {
+    if strings.ContainsRune(`[]:*?/\`, r) { return '_' }
+    return r
+  }, name)
+
+  if name == "" { name = "Sheet" }
+  if runes := [] rune(name); len(runes) > 31 {
+    name = string(runes[:31])
+  }
+
+  unique_name := name
+  for suffix := 2; used[unique_name]; suffix++ {
+    suffix_str  := fmt.Sprintf(" (%d)", suffix)
+    runes       := [] rune(name)
+    if len(runes) + len(suffix_str) > 31 {
+      runes = runes[:31 - len(suffix_str)]
+    }
+    unique_name = string(runes) + suffix_str
+  }
+
+  used[unique_name] = true
+  return unique_name
+}
+
+
+const xlsxContentTypesHead string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+`
+
+const xlsxRootRels string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>
+`
+
+const xlsxStyles string = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="0.00"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="4">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>
+`
+
+
+func xlsxWriteSheet (io_writer io.Writer, sheet * XlsxSheet) error {
+  var builder strings.Builder
+
+  builder.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+  builder.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + "\n")
+
+  if len(sheet.Column_widths) > 0 {
+    builder.WriteString("<cols>")
+    for column_i, width := range sheet.Column_widths {
+      fmt.Fprintf(&builder, `<col min="%d" max="%d" width="%g" customWidth="1"/>`, column_i+1, column_i+1, width)
+    }
+    builder.WriteString("</cols>\n")
+  }
+
+  builder.WriteString("<sheetData>\n")
+  for row_i, row := range sheet.Rows {
+    fmt.Fprintf(&builder, `<row r="%d">`, row_i+1)
+    for column_i, cell := range row {
+      reference := xlsxColumnName(column_i) + strconv.Itoa(row_i+1)
+      if cell.Is_number {
+        fmt.Fprintf(
+          &builder, `<c r="%s" s="%d"><v>%s</v></c>`,
+          reference, cell.Style, strconv.FormatFloat(cell.Number, 'f', -1, 64),
+        )
+      } else if cell.Text != "" {
+        fmt.Fprintf(
+          &builder, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+          reference, cell.Style, xlsxEscape(cell.Text),
+        )
+      }
+    }
+    builder.WriteString("</row>\n")
+  }
+  builder.WriteString("</sheetData>\n</worksheet>\n")
+
+  _, err := io.WriteString(io_writer, builder.String())
+  return err
+}
+
+
+func (workbook *XlsxWorkbook) Write (io_writer io.Writer) (err error) {
+  zip_writer := zip.NewWriter(io_writer)
+
+  writePart := func (name, contents string) error {
+    part, err := zip_writer.Create(name)
+    if err != nil { return err }
+    _, err = io.WriteString(part, contents)
+    return err
+  }
+
+  sheets := workbook.Sheets
+  if len(sheets) == 0 {
+    sheets = [] XlsxSheet { { Name: "Sheet1" } }  // a workbook needs a sheet
+  }
+
+  var content_types, workbook_xml, workbook_rels strings.Builder
+
+  content_types.WriteString(xlsxContentTypesHead)
+
+  workbook_xml.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+  workbook_xml.WriteString(
+    `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"` +
+    ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` + "\n<sheets>\n",
+  )
+
+  workbook_rels.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+  workbook_rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + "\n")
+
+  used_names := make(map [string] bool)
+
+  for sheet_i := range sheets {
+    sheet_id := sheet_i + 1
+    fmt.Fprintf(
+      &content_types,
+      `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` + "\n",
+      sheet_id,
+    )
+    fmt.Fprintf(
+      &workbook_xml, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>` + "\n",
+      xlsxEscape(xlsxSheetName(sheets[sheet_i].Name, used_names)), sheet_id, sheet_id,
+    )
+    fmt.Fprintf(
+      &workbook_rels,
+      `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>` + "\n",
+      sheet_id, sheet_id,
+    )
+  }
+
+  styles_id := len(sheets) + 1
+  fmt.Fprintf(
+    &workbook_rels,
+    `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` + "\n",
+    styles_id,
+  )
+
+  content_types.WriteString("</Types>\n")
+  workbook_xml.WriteString("</sheets>\n</workbook>\n")
+  workbook_rels.WriteString("</Relationships>\n")
+
+  if err = writePart("[Content_Types].xml", content_types.String()); err != nil { return err }
+  if err = writePart("_rels/.rels", xlsxRootRels); err != nil { return err }
+  if err = writePart("xl/workbook.xml", workbook_xml.String()); err != nil { return err }
+  if err = writePart("xl/_rels/workbook.xml.rels", workbook_rels.String()); err != nil { return err }
+  if err = writePart("xl/styles.xml", xlsxStyles); err != nil { return err }
+
+  for sheet_i := range sheets {
+    part, err := zip_writer.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", sheet_i+1))
+    if err != nil { return err }
+    if err = xlsxWriteSheet(part, &sheets[sheet_i]); err != nil { return err }
+  }
+
+  return zip_writer.Close()
+}
@@ -0,0 +1,35 @@
+package web
+
+
+import (
+  "log"
+  "net/http"
+  "strings"
+
+  stt "gill-dashboard/pkg/stt_records"
+)
+
+
+func ServeCalendar (res http.ResponseWriter, req * http.Request, records [] stt.ActivityRecord) {
+  /*
+    Serve records as an iCalendar feed, filtered by the "after", "before" and
+    "category" query parameters. Record times are only placed correctly in
+    calendar apps when STT_TIMEZONE is set; otherwise they are taken as UTC.
+  */
+
+  filter, err := stt.ActivityRecordFilterFromQuery(req.URL.Query())
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  calendar_name := "Time tracking"
+  if len(filter.Categories) > 0 {
+    calendar_name += ": " + strings.Join(filter.Categories, ", ")
+  }
+
+  res.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+  if err := stt.ActivityRecordsWriteIcal(res, filter.Apply(records), calendar_name); err != nil {
+    log.Println("/calendar.ics:", err)
+  }
+}
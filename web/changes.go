@@ -0,0 +1,116 @@
+package web
+
+
+import (
+  "fmt"
+  "time"
+  "net/http"
+  "strings"
+  htmlTemplate "html/template"
+
+  stt "gill-dashboard/pkg/stt_records"
+)
+
+
+func writeRecordRow (builder * strings.Builder, record stt.ActivityRecord) {
+  fmt.Fprintf(
+    builder,
+    "<td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td>",
+    record.Time_started.Format(time.DateTime),
+    record.Time_ended.Format(time.DateTime),
+    htmlTemplate.HTMLEscapeString(record.Activity_name),
+    htmlTemplate.HTMLEscapeString(strings.Join(record.Categories, ", ")),
+    record.Duration,
+    htmlTemplate.HTMLEscapeString(record.Comment),
+  )
+}
+
+
+func writeRecordsTable (builder * strings.Builder, title string, records [] stt.ActivityRecord) {
+  fmt.Fprintf(builder, "<h2>%s (%d)</h2>\n", title, len(records))
+  if len(records) == 0 { return }
+
+  builder.WriteString("<table>\n")
+  builder.WriteString("<tr><th>Started</th><th>Ended</th><th>Activity</th><th>Categories</th><th>Duration</th><th>Comment</th></tr>\n")
+  for _, record := range records {
+    builder.WriteString("<tr>")
+    writeRecordRow(builder, record)
+    builder.WriteString("</tr>\n")
+  }
+  builder.WriteString("</table>\n")
+}
+
+
+func ServeChanges (
+  res           http.ResponseWriter,
+  req         * http.Request,
+  diff          stt.ActivityRecordsDiff,
+  old_snapshot, new_snapshot * stt.SttSnapshot,
+) {
+  main_builder := strings.Builder {}
+  main_builder.WriteString(`<h1>Changes since the last sync</h1>`)
+
+  if new_snapshot == nil {
+    main_builder.WriteString("<p>No snapshots have been taken yet.</p>")
+  } else if old_snapshot == nil {
+    fmt.Fprintf(
+      &main_builder, "<p>Only one snapshot exists (%s); every record is new.</p>\n",
+      new_snapshot.Time.Local().Format(time.DateTime),
+    )
+  } else {
+    fmt.Fprintf(
+      &main_builder, "<p>Comparing snapshot %s to %s.</p>\n",
+      old_snapshot.Time.Local().Format(time.DateTime),
+      new_snapshot.Time.Local().Format(time.DateTime),
+    )
+  }
+
+  writeRecordsTable(&main_builder, "Added",   diff.Added)
+  writeRecordsTable(&main_builder, "Removed", diff.Removed)
+
+  fmt.Fprintf(&main_builder, "<h2>Changed (%d)</h2>\n", len(diff.Changed))
+  if len(diff.Changed) > 0 {
+    main_builder.WriteString("<table>\n")
+    main_builder.WriteString("<tr><th></th><th>Started</th><th>Ended</th><th>Activity</th><th>Categories</th><th>Duration</th><th>Comment</th></tr>\n")
+    for _, change := range diff.Changed {
+      main_builder.WriteString(`<tr class="old"><td>Before</td>`)
+      writeRecordRow(&main_builder, change.Old)
+      main_builder.WriteString("</tr>\n")
+      main_builder.WriteString(`<tr class="new"><td>After</td>`)
+      writeRecordRow(&main_builder, change.New)
+      main_builder.WriteString("</tr>\n")
+    }
+    main_builder.WriteString("</table>\n")
+  }
+
+  template_data := BaseTemplate {
+    Title: "Changes",
+    Main: htmlTemplate.HTML(main_builder.String()),
+
+    Head: `
+    <style>
+      h1 {
+        border-bottom: 1px solid #8888;
+        margin: 1vh;
+      }
+
+      main { padding: 0 1em; }
+
+      table {
+        border-collapse: collapse;
+        font-size: 0.9em;
+      }
+
+      th, td {
+        text-align: left;
+        padding: 0.2em 0.6em;
+        border-bottom: 1px solid #8884;
+      }
+
+      tr.old { color: #888; }
+      tr.new > td { border-bottom-color: #888; }
+    </style>`,
+  }
+
+  base_template.Execute(res, template_data)
+}
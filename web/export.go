@@ -0,0 +1,64 @@
+package web
+
+
+import (
+  "fmt"
+  "log"
+  "net/http"
+  "strings"
+
+  stt "gill-dashboard/pkg/stt_records"
+)
+
+
+func ServeExport (res http.ResponseWriter, req * http.Request, records [] stt.ActivityRecord) {
+  /*
+    Export records as /export?format=json|ndjson|markdown|csv, filtered by the
+    "after", "before" and "category" query parameters. With "totals", the
+    JSON formats export per-activity totals instead of records, and with
+    "group", such as group=week,activity, aggregate groups.
+  */
+
+  query := req.URL.Query()
+
+  format := query.Get("format")
+  if format == "" {
+    format = "json"
+  }
+
+  content_type, found := stt.EXPORT_CONTENT_TYPES[format]
+  if ! found {
+    http.Error(
+      res,
+      fmt.Sprintf("unknown export format \"%s\", expected one of: %s", format, strings.Join(stt.EXPORT_FORMATS, ", ")),
+      http.StatusBadRequest,
+    )
+    return
+  }
+
+  filter, err := stt.ActivityRecordFilterFromQuery(query)
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  group_by, err := stt.ParseAggregateKeys(query.Get("group"))
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  _, totals := query["totals"]
+
+  // By the time writing fails, the response has started, so the error can
+  // only be logged
+  res.Header().Set("Content-Type", content_type)
+  if len(group_by) > 0 {
+    err = stt.ActivityRecordsExportGroups(res, filter.Apply(records), format, group_by)
+  } else {
+    err = stt.ActivityRecordsExport(res, filter.Apply(records), format, totals)
+  }
+  if err != nil {
+    log.Println("/export:", err)
+  }
+}
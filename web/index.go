@@ -4,39 +4,43 @@ package web
 import (
   "embed"
   "fmt"
+  "os"
   "time"
   "net/http"
   "strings"
   htmlTemplate "html/template"
-  textTemplate "text/template"
 
   stt "gill-dashboard/pkg/stt_records"
 )
 
 //go:embed templates/*.html
 var templates       embed.FS
-var base_template * textTemplate.Template
+var base_template * htmlTemplate.Template
 
 func init () {
-  fmt.Println("Loading template")
-  base_template = textTemplate.Must(textTemplate.ParseFS(templates, "templates/base.html"))
+  fmt.Fprintln(os.Stderr, "Loading template")
+  base_template = htmlTemplate.Must(htmlTemplate.ParseFS(templates, "templates/base.html"))
 }
 
 
 type BaseTemplate struct {
+  /*
+    The Title is escaped; the Head and Main are markup, already escaped where
+    they need to be.
+  */
   Title string;
-  Head  string;
-  Main  string;
+  Head  htmlTemplate.HTML;
+  Main  htmlTemplate.HTML;
 }
 
 
-func pageHtmlIndex () {
-  fmt.Println(htmlTemplate.HTMLEscape)
-  fmt.Println(textTemplate.Must)
-}
-
-
-func ServeIndex (res http.ResponseWriter, req * http.Request, records [] stt.ActivityRecord) {
+func ServeIndex (
+  res                 http.ResponseWriter,
+  req               * http.Request,
+  records           [] stt.ActivityRecord,
+  calendar_records  [] stt.ActivityRecord,
+  heading             string,
+) {
   // Iterate through records, and get the total number o
   records_duration := time.Duration(0)
   final_date       := time.Time {}
@@ -49,12 +53,35 @@ func ServeIndex (res http.ResponseWriter, req * http.Request, records [] stt.Act
   }
 
   main_builder := strings.Builder {}
-  main_builder.WriteString(`<h1>Productivity: last seven days</h1>`)
+  fmt.Fprintf(&main_builder, `<h1>%s</h1>`, htmlTemplate.HTMLEscapeString(heading))
+  // Each chart has a table of its data beside it, shown with the "table"
+  // parameter, and otherwise a click away
+  details_open := ""
+  if req.URL.Query().Has("table") {
+    details_open = " open"
+  }
+  writeChart := func (name string, records [] stt.ActivityRecord, options * stt.ActivityRecordChartOptions) error {
+    chart, err := stt.LookupChart(name)
+    if err != nil { return err }
+    if err = chart.Render(&main_builder, records, options); err != nil { return err }
+    fmt.Fprintf(&main_builder, "<details%s><summary>Data</summary>\n", details_open)
+    if err = chart.Table(records, options).WriteHTML(&main_builder); err != nil { return err }
+    main_builder.WriteString("</details>\n")
+    return nil
+  }
+
   main_builder.WriteString("<figure>\n")
-  main_builder.WriteString(stt.ActivityRecordsPlotPieChart(records, &stt.ActivityRecordChartOptions {
+  pie_options := stt.ActivityRecordChartOptions {
     Width: "100%",
     Height: "100%",
-  }))
+    Title: heading,
+    Legend: true,
+    Other_percent: 2,
+  }
+  if err := writeChart("pie", records, &pie_options); err != nil {
+    http.Error(res, err.Error(), http.StatusInternalServerError)
+    return
+  }
   main_builder.WriteString(`<figcaption>`)
   main_builder.WriteString(``)
   fmt.Fprintf(&main_builder, "<p>Number of records: %d\n</p>", len(records))
@@ -64,11 +91,21 @@ func ServeIndex (res http.ResponseWriter, req * http.Request, records [] stt.Act
   main_builder.WriteString(`</figcaption>`)
   main_builder.WriteString("</figure>")
 
+  main_builder.WriteString(`<figure class="calendar">` + "\n")
+  err := writeChart("calendar", calendar_records, &stt.ActivityRecordChartOptions {
+    Width: "100%",
+  })
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusInternalServerError)
+    return
+  }
+  main_builder.WriteString("</figure>")
+
   template_data := BaseTemplate {
     Title: "Home",
-    Main: main_builder.String(),
+    Main: htmlTemplate.HTML(main_builder.String()),
 
-    Head: `
+    Head: htmlTemplate.HTML(`
     <style>
       h1 {
         border-bottom: 1px solid #8888;
@@ -97,10 +134,16 @@ func ServeIndex (res http.ResponseWriter, req * http.Request, records [] stt.Act
         }
       }
 
+      figure.calendar {
+        flex-grow: 0;
+        &> svg { max-height: none; }
+      }
+
       img {
         max-width: 100%;
       }
-    </style>`,
+` + chart_table_style + `
+    </style>`),
   }
 
   base_template.Execute(res, template_data)
@@ -0,0 +1,44 @@
+package web
+
+
+import (
+  "net/http"
+  "strings"
+  htmlTemplate "html/template"
+
+  stt "gill-dashboard/pkg/stt_records"
+)
+
+
+// The style of charts' data tables, on their own or beside their charts
+const chart_table_style string = `
+      table.chart-data {
+        border-collapse: collapse;
+        font-size: 0.9em;
+
+        & caption { text-align: start; font-weight: bold; padding: 0.25em 0; }
+        & th, & td { padding: 0.2em 0.6em; border-bottom: 1px solid #8884; }
+        & td { text-align: end; font-variant-numeric: tabular-nums; }
+        & th[scope="row"] { text-align: start; font-weight: normal; }
+        & tfoot th, & tfoot td { font-weight: bold; }
+      }`
+
+
+func ServeChartTable (
+  res     http.ResponseWriter,
+  req   * http.Request,
+  table * stt.ChartTable,
+) {
+  main_builder := strings.Builder {}
+  if err := table.WriteHTML(&main_builder); err != nil {
+    http.Error(res, err.Error(), http.StatusInternalServerError)
+    return
+  }
+
+  template_data := BaseTemplate {
+    Title: table.Caption,
+    Main:  htmlTemplate.HTML(main_builder.String()),
+    Head:  htmlTemplate.HTML("\n    <style>" + chart_table_style + "\n    </style>"),
+  }
+  base_template.Execute(res, template_data)
+}
@@ -0,0 +1,52 @@
+package web
+
+
+import (
+  "log"
+  "net/http"
+  "time"
+
+  stt "gill-dashboard/pkg/stt_records"
+)
+
+
+func ServeTimesheet (res http.ResponseWriter, req * http.Request, records [] stt.ActivityRecord) {
+  /*
+    Serve an XLSX timesheet of records, filtered by the "after", "before" and
+    "category" query parameters. "group" is activity or tag, "round" a
+    duration such as 15m, and "round_mode" nearest, up or down.
+  */
+
+  query := req.URL.Query()
+
+  filter, err := stt.ActivityRecordFilterFromQuery(query)
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  options := & stt.TimesheetOptions {
+    Group_by:   query.Get("group"),
+    Round_mode: query.Get("round_mode"),
+  }
+
+  if round_str := query.Get("round"); round_str != "" {
+    options.Round_to, err = time.ParseDuration(round_str)
+    if err != nil {
+      http.Error(res, "invalid rounding: " + err.Error(), http.StatusBadRequest)
+      return
+    }
+  }
+
+  workbook, err := stt.ActivityRecordsTimesheet(filter.Apply(records), options)
+  if err != nil {
+    http.Error(res, err.Error(), http.StatusBadRequest)
+    return
+  }
+
+  res.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+  res.Header().Set("Content-Disposition", `attachment; filename="timesheet.xlsx"`)
+  if err := workbook.Write(res); err != nil {
+    log.Println("/timesheet.xlsx:", err)
+  }
+}